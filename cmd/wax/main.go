@@ -0,0 +1,168 @@
+// Command wax prepares views for production.
+//
+//	wax build -views ./views -o views.bundle [-node-modules] [-import-map importmap.json] [-tsconfig tsconfig.json]
+//	wax ssg -manifest site.json
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/michal-laskowski/wax"
+)
+
+const usage = `usage: wax <command> [flags]
+
+commands:
+  build    transpile and validate views into bundle for wax.NewBundleViewResolver
+  ssg      render static site described by JSON manifest
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "build":
+		err = build(ctx, os.Args[2:])
+	case "ssg":
+		err = ssg(ctx, os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		printError(err)
+		os.Exit(1)
+	}
+}
+
+func build(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	views := flags.String("views", "./views", "views directory")
+	out := flags.String("o", "views.bundle", "output bundle file")
+	var resolve resolveFlags
+	flags.BoolVar(&resolve.NodeModules, "node-modules", false, "resolve bare imports from node_modules of views directory")
+	flags.StringVar(&resolve.ImportMap, "import-map", "", "import map file, JSON with \"imports\"")
+	flags.StringVar(&resolve.TSConfig, "tsconfig", "", "tsconfig.json with paths, relative to views directory")
+	flags.Parse(args)
+
+	options, err := resolve.options(func(p string) string { return p })
+	if err != nil {
+		return err
+	}
+	engine := wax.New(wax.NewFsViewResolver(os.DirFS(*views), options...))
+	var bundle bytes.Buffer
+	if err := engine.WriteBundle(ctx, &bundle); err != nil {
+		return err
+	}
+	return os.WriteFile(*out, bundle.Bytes(), 0o644)
+}
+
+// resolveFlags configure resolving of bare import specifiers, like options of wax.NewFsViewResolver.
+type resolveFlags struct {
+	NodeModules bool   `json:"nodeModules"`
+	ImportMap   string `json:"importMap"`
+	// TSConfig is path in views directory
+	TSConfig string `json:"tsconfig"`
+}
+
+// options returns view resolver options, import map file path is mapped with path.
+func (f resolveFlags) options(path func(string) string) ([]wax.FSViewResolverOption, error) {
+	var options []wax.FSViewResolverOption
+	if f.NodeModules {
+		options = append(options, wax.WithNodeModules())
+	}
+	if f.ImportMap != "" {
+		data, err := os.ReadFile(path(f.ImportMap))
+		if err != nil {
+			return nil, err
+		}
+		var importMap struct {
+			Imports map[string]string `json:"imports"`
+		}
+		if err := json.Unmarshal(data, &importMap); err != nil {
+			return nil, fmt.Errorf("import map '%s': %w", f.ImportMap, err)
+		}
+		options = append(options, wax.WithImportMap(importMap.Imports))
+	}
+	if f.TSConfig != "" {
+		options = append(options, wax.WithTSConfig(f.TSConfig))
+	}
+	return options, nil
+}
+
+// ssgManifest describes site for `wax ssg`. Paths are relative to the manifest file.
+type ssgManifest struct {
+	Views   string      `json:"views"`
+	Out     string      `json:"out"`
+	Static  string      `json:"static"`
+	BaseURL string      `json:"baseURL"`
+	Routes  []wax.Route `json:"routes"`
+	resolveFlags
+}
+
+func ssg(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("ssg", flag.ExitOnError)
+	manifestFile := flags.String("manifest", "site.json", "site manifest")
+	flags.Parse(args)
+
+	data, err := os.ReadFile(*manifestFile)
+	if err != nil {
+		return err
+	}
+	manifest := ssgManifest{Views: "./views", Out: "./public"}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("manifest '%s': %w", *manifestFile, err)
+	}
+
+	dir := filepath.Dir(*manifestFile)
+	relative := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i := range manifest.Routes {
+		manifest.Routes[i].ModelFile = relative(manifest.Routes[i].ModelFile)
+	}
+
+	options, err := manifest.options(relative)
+	if err != nil {
+		return err
+	}
+	engine := wax.New(wax.NewFsViewResolver(os.DirFS(relative(manifest.Views)), options...))
+	return engine.Generate(ctx, wax.GenerateOptions{
+		OutDir:    relative(manifest.Out),
+		Routes:    manifest.Routes,
+		StaticDir: relative(manifest.Static),
+		BaseURL:   manifest.BaseURL,
+	})
+}
+
+func printError(err error) {
+	if errs, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range errs.Unwrap() {
+			printError(err)
+		}
+		return
+	}
+	var waxError wax.Error
+	if errors.As(err, &waxError) {
+		fmt.Fprintln(os.Stderr, waxError.ErrorDetailed())
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
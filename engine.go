@@ -1,15 +1,18 @@
 package wax
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/url"
-	"sync"
+	"strings"
 
 	"github.com/dop251/goja"
 )
 
+// ViewResolver locates views and modules and reads their content.
+// Errors of files which do not exist should wrap fs.ErrNotExist, e.g. missing layout files are skipped.
 type ViewResolver interface {
 	ResolveViewFile(viewName string) (*url.URL, error)
 	ResolveModuleFile(fromModule ModuleMeta, importPath string) (*url.URL, error)
@@ -25,8 +28,8 @@ func New(viewResolver ViewResolver, options ...Option) *Engine {
 		globals:       make(map[string]any),
 		globalScripts: []string{},
 		viewResolver:  viewResolver,
-		cache:         make(map[string]*goja.Program),
-		transpiler:    NewTreeSitterTranspiler(),
+		cache:         NewLRUProgramCache(DefaultProgramCacheSize),
+		transpiler:    defaultTranspiler(),
 	}
 	for _, option := range options {
 		option(result)
@@ -51,12 +54,18 @@ type Error struct {
 	Stack string
 	Phase string
 	Err   error
+	// Line and Column point to position in original SourceFile, when known.
+	// SourceFile is module of the top stack frame mapped to source - File or module it imports.
+	Line       int
+	Column     int
+	SourceFile url.URL
 }
 
 var (
 	PhaseLoading     = "load"
 	PhaseCompilation = "compile"
 	PhaseExec        = "execute"
+	PhaseLimit       = "limit"
 	PhaseOther       = "other"
 )
 
@@ -67,6 +76,10 @@ func (e Error) Error() string {
 	return fmt.Sprintf("wax: %s", e.Err.Error())
 }
 
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
 func (e Error) ErrorDetailed() string {
 	if e.Phase == PhaseExec {
 		return fmt.Sprintf("wax error: %s: %s - %s - %s", e.Phase, e.File.Path, e.Err.Error(), e.Stack)
@@ -80,10 +93,18 @@ type (
 		globals       map[string]any
 		globalScripts []string
 		viewResolver  ViewResolver
-		cache         map[string]*goja.Program
-		cacheMu       sync.RWMutex
-
-		transpiler TypeScriptTranspiler
+		cache         ProgramCache
+		pool          chan *jsRuntime
+		limits        renderLimits
+		streaming     streamOptions
+		layoutFile    string
+		services      map[string]any
+		modules       map[string]registeredModule
+		importCycles  bool
+		sharedModules map[string]bool
+
+		transpiler        TypeScriptTranspiler
+		transpileCacheDir string
 	}
 )
 
@@ -91,6 +112,8 @@ type RunBinding struct {
 	ViewResolver ViewResolver
 	Globals      map[string]any
 	Model        any
+	// Services are passed to view loader, next to services registered with WithService.
+	Services map[string]any
 }
 
 type ModuleMeta struct {
@@ -109,126 +132,222 @@ type ModuleMeta struct {
 // }
 
 func (e *Engine) RenderWith(out io.Writer, viewName string, binding RunBinding) error {
-	context := runContext{
+	return e.RenderWithContext(context.Background(), out, viewName, binding)
+}
+
+func (e *Engine) Render(out io.Writer, viewName string, model any) error {
+	return e.RenderContext(context.Background(), out, viewName, model)
+}
+
+// RenderWithContext renders view and stops JS execution when ctx is done.
+// View name "file#Export" renders single export of the file as partial (see RenderPartial).
+func (e *Engine) RenderWithContext(ctx context.Context, out io.Writer, viewName string, binding RunBinding) error {
+	rc := runContext{
 		Model:        binding.Model,
 		ViewResolver: binding.ViewResolver,
 		Globals:      binding.Globals,
+		Services:     binding.Services,
 		out:          out,
+		ctx:          ctx,
 	}
-	viewURI, err := context.ViewResolver.ResolveViewFile(viewName)
+	viewFile := viewName
+	if file, exportName, isPartial := strings.Cut(viewName, "#"); isPartial {
+		viewFile, viewName = file, exportName
+		rc.partial = true
+	}
+	viewURI, err := rc.ViewResolver.ResolveViewFile(viewFile)
 	if err != nil {
 		return err
 	}
 
-	if err := e.renderView(viewURI, viewName, &context); err != nil {
+	if err := e.renderView(viewURI, viewName, &rc); err != nil {
 		// must be always WaxError
 		return err
 	}
 	return nil
 }
 
-func (e *Engine) Render(out io.Writer, viewName string, model any) error {
-	return e.RenderWith(out, viewName, RunBinding{
+func (e *Engine) RenderContext(ctx context.Context, out io.Writer, viewName string, model any) error {
+	return e.RenderWithContext(ctx, out, viewName, RunBinding{
 		Model:        model,
 		ViewResolver: e.viewResolver,
 	})
 }
 
+// RenderPartial renders component exported as exportName from file, with props as its argument.
+// Unlike views there is no fallback to default export and layouts are not applied.
+func (e *Engine) RenderPartial(out io.Writer, file string, exportName string, props any) error {
+	return e.RenderPartialContext(context.Background(), out, file, exportName, props)
+}
+
+func (e *Engine) RenderPartialContext(ctx context.Context, out io.Writer, file string, exportName string, props any) error {
+	return e.RenderContext(ctx, out, file+"#"+exportName, props)
+}
+
 type runContext struct {
 	ViewResolver ViewResolver
 	Globals      map[string]any
+	Services     map[string]any
 	Model        any
 	out          io.Writer
+	ctx          context.Context
+	partial      bool
+	loop         *eventLoop
 }
 
 const InternalError = "internal error"
 
 func (e *Engine) load(context *runContext, wax *waxJSObj, viewFilePath *url.URL) (goja.Value, error) {
 	if moduleExports := wax.GetModule(viewFilePath.String()); moduleExports != nil {
+		if cycle := wax.importCycle(viewFilePath); cycle != nil && !e.importCycles {
+			return nil, importCycleError(cycle)
+		}
 		return moduleExports, nil
 	}
+	if m, ok := e.registeredModule(viewFilePath); ok && isNativeModule(m) {
+		return wax.defineNativeModule(viewFilePath, m.exports), nil
+	}
 
 	moduleMeta := ModuleMeta{
 		URL: viewFilePath,
 	}
 	globalImport := wax.DefineModule(&moduleMeta)
-	p, err := e.loadModuleImport(&moduleMeta, context)
+	compiled, err := e.loadModuleImport(&moduleMeta, context)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = wax.vm.RunProgram(p)
+	// modules imported by shared module are shared too
+	shared := wax.sharedDepth > 0 || e.isSharedModule(viewFilePath, compiled)
+	if shared {
+		wax.sharedDepth++
+	}
+	wax.loading = append(wax.loading, viewFilePath)
+	_, err = wax.vm.RunProgram(compiled.program)
+	wax.loading = wax.loading[:len(wax.loading)-1]
+	if shared {
+		wax.sharedDepth--
+		if err == nil {
+			err = wax.freezeExports(globalImport)
+			wax.shared = append(wax.shared, viewFilePath.String())
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 	return globalImport, nil
 }
 
-func (e *Engine) loadModuleImport(module *ModuleMeta, context *runContext) (*goja.Program, error) {
-	key := module.URL.String()
-	e.cacheMu.RLock()
-	pc, fromCache := e.cache[key]
-	if fromCache {
-		e.cacheMu.RUnlock()
-		return pc, nil
-	}
+func (e *Engine) loadModuleImport(module *ModuleMeta, context *runContext) (*CompiledModule, error) {
+	return e.compileModule(module.URL, context.ViewResolver)
+}
 
-	e.cacheMu.RUnlock()
-	e.cacheMu.Lock()
-	defer e.cacheMu.Unlock()
+// CompiledModule is transpiled and compiled module, ready to run.
+type CompiledModule struct {
+	program   *goja.Program
+	sourceMap *SourceMap
+	imports   []string
+	shared    bool
+}
+
+// compileModule returns cached module or transpiles and compiles it. Failed modules are not cached.
+func (e *Engine) compileModule(moduleURL *url.URL, viewResolver ViewResolver) (*CompiledModule, error) {
+	if cached, fromCache := e.cache.Get(moduleURL); fromCache {
+		return cached, nil
+	}
 
-	moduleCode, err := context.ViewResolver.GetContent(*module.URL)
+	key := moduleURL.String()
+	jsCode, sourceMap, err := e.moduleCode(moduleURL, viewResolver)
 	if err != nil {
 		return nil, err
 	}
 
-	jsCode, err := e.transpiler.Transpile(key, moduleCode)
+	jsCode = wrapModule(jsCode, key)
+	program, err := goja.Compile(key, jsCode, true)
 	if err != nil {
-		return nil, Error{
-			File:  *module.URL,
-			Phase: PhaseLoading,
+		compileError := Error{
+			File:  *moduleURL,
+			Phase: PhaseCompilation,
 			Err:   err,
 		}
+		if line, column, ok := compileErrorPosition(err); ok {
+			compileError.Line, compileError.Column, ok = sourcePosition(sourceMap, line, column)
+			if ok {
+				compileError.SourceFile = *moduleURL
+			}
+		}
+		return nil, compileError
+	}
+
+	compiled := &CompiledModule{
+		program:   program,
+		sourceMap: sourceMap,
+		imports:   findImports(jsCode),
+		shared:    hasSharedDirective(jsCode[len(moduleWrapperPrefix):]),
+	}
+	e.cache.Put(moduleURL, compiled)
+	return compiled, nil
+}
+
+func (e *Engine) moduleCode(moduleURL *url.URL, viewResolver ViewResolver) (string, *SourceMap, error) {
+	var moduleCode string
+	if m, ok := e.registeredModule(moduleURL); ok {
+		if isNativeModule(m) {
+			return "", nil, fmt.Errorf("native module '%s' has no source", moduleURL.Opaque)
+		}
+		moduleCode = m.source
+	} else if provider, ok := viewResolver.(TranspiledContentProvider); ok && !isAssetModule(moduleURL) {
+		return provider.GetTranspiledContent(*moduleURL)
+	} else {
+		var err error
+		moduleCode, err = viewResolver.GetContent(*moduleURL)
+		if err != nil {
+			return "", nil, err
+		}
 	}
 
-	jsCode = fmt.Sprintf(";(function (module) {;%s;})(wax.GetModule('%s'));", jsCode, key)
-	compiled, err := goja.Compile(key, jsCode, true)
-	e.cache[key] = compiled
+	jsCode, sourceMap, err := e.transformModule(moduleURL, moduleCode)
 	if err != nil {
-		return nil, Error{
-			File:  *module.URL,
-			Phase: PhaseCompilation,
+		return "", nil, Error{
+			File:  *moduleURL,
+			Phase: PhaseLoading,
 			Err:   err,
 		}
 	}
-	return compiled, nil
+	return jsCode, sourceMap, nil
 }
 
 func (e *Engine) renderView(moduleURI *url.URL, viewName string, context *runContext) error {
 	viewModuleMeta := ModuleMeta{URL: moduleURI, isMain: true}
+	if err := context.ctx.Err(); err != nil {
+		return Error{
+			File:  *moduleURI,
+			Phase: PhaseExec,
+			Err:   err,
+		}
+	}
 
-	vm := goja.New()
-	waxObj := newWaxObj(e, vm, context)
-	vm.GlobalObject().DefineDataProperty("wax", waxObj.obj, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+	cancel := e.limits.apply(context)
+	defer cancel()
 
-	for k, v := range e.globals {
-		vm.GlobalObject().Set(k, v)
+	rt, err := e.acquireRuntime(viewModuleMeta, context)
+	if err != nil {
+		return err
 	}
-	for k, v := range context.Globals {
-		vm.GlobalObject().Set(k, v)
+	context.loop = newEventLoop(context.ctx, rt.vm)
+	context.ctx = context.loop.ctx
+	stopWatching := watchContext(context.ctx, rt.vm)
+	err = e.execView(rt, viewModuleMeta, viewName, context)
+	stopWatching()
+	e.releaseRuntime(rt, err == nil)
+	if err != nil {
+		return e.mapStackToSource(interruptionError(context.ctx, *moduleURI, err))
 	}
+	return nil
+}
 
-	for _, v := range e.globalScripts {
-		moduleURI, err := context.ViewResolver.ResolveModuleFile(viewModuleMeta, v)
-		if err != nil {
-			return err
-		}
-
-		_, err = e.load(context, waxObj, moduleURI)
-		if err != nil {
-			return err
-		}
-	}
+func (e *Engine) execView(rt *jsRuntime, viewModuleMeta ModuleMeta, viewName string, context *runContext) error {
+	vm, waxObj, moduleURI := rt.vm, rt.wax, viewModuleMeta.URL
 
 	mainModule, err := e.load(context, waxObj, moduleURI)
 	if err != nil {
@@ -238,17 +357,19 @@ func (e *Engine) renderView(moduleURI *url.URL, viewName string, context *runCon
 		return fmt.Errorf("main module not loaded")
 	}
 
-	mainModuleExports, ok := mainModule.(*goja.Object).Get("exports").(*goja.Object)
-	if !ok {
-		panic("invalid state: no exports")
-	}
-
-	viewValue := mainModuleExports.Get(viewName)
-	if viewValue == nil {
+	viewValue := moduleExports(mainModule).Get(viewName)
+	if viewValue == nil && (!context.partial || viewName == "default") {
 		viewValue = mainModule.ToObject(vm).Get("default")
 	}
 
 	if viewValue == nil {
+		if context.partial {
+			return Error{
+				File:  *viewModuleMeta.URL,
+				Phase: PhaseLoading,
+				Err:   fmt.Errorf("could not find export '%s'", viewName),
+			}
+		}
 		return Error{
 			File:  *viewModuleMeta.URL,
 			Phase: PhaseLoading,
@@ -265,15 +386,43 @@ func (e *Engine) renderView(moduleURI *url.URL, viewName string, context *runCon
 		}
 	}
 
-	gojaErr := vm.Try(func() {
+	var layouts []layout
+	if !context.partial {
+		layouts, err = e.resolveLayouts(context, waxObj, viewModuleMeta, mainModule)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer := newWriter(context.out, vm)
+	writer.stream = &streamState{streamOptions: e.streaming}
+	writer.loop = context.loop
+	loader, hasLoader := loaderExport(mainModule)
+	gojaErr := try(vm, func() {
+		if hasLoader && !context.partial {
+			context.Model = e.runLoader(rt, loader, context)
+		}
 		view, err := asCallable(goja.Undefined(), vm.ToValue(context.Model))
 		if err != nil {
 			panic(err)
 		}
+		view = wrapWithLayouts(vm, view, layouts, context.Model)
 
-		writer := newWriter(context.out, vm)
 		writer.process(view, vm)
+		writer.settle()
+		writer.writeDeferredChunks()
 	})
+	if writer.err != nil {
+		phase := PhaseExec
+		if isLimitError(writer.err) {
+			phase = PhaseLimit
+		}
+		return Error{
+			File:  *viewModuleMeta.URL,
+			Phase: phase,
+			Err:   writer.err,
+		}
+	}
 
 	if gojaErr != nil {
 		stack := gojaErr.Error()
@@ -0,0 +1,75 @@
+package wax
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// assetExtensions are extensions of non-script files importable as modules.
+var assetExtensions = []string{".json", ".css", ".svg"}
+
+// rawImport is import query giving file content as string, e.g. "./snippet.html?raw".
+const rawImport = "raw"
+
+// splitImportQuery splits import path from its query, kept in fragment of resolved module URL.
+func splitImportQuery(importPath string) (string, string) {
+	importPath, query, _ := strings.Cut(importPath, "?")
+	return importPath, query
+}
+
+// isAssetModule reports whether module is non-script file converted by assetModule.
+// Assets are never transpiled, also in bundles.
+func isAssetModule(moduleURL *url.URL) bool {
+	if moduleURL.Fragment != "" {
+		return true
+	}
+	ext := path.Ext(moduleURL.Path)
+	for _, e := range assetExtensions {
+		if e == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// assetModule converts imported non-script file to module code.
+func assetModule(moduleURL *url.URL, content string) (string, error) {
+	switch {
+	case moduleURL.Fragment == rawImport:
+		return "module.default = " + jsString(content) + ";", nil
+	case moduleURL.Fragment != "":
+		return "", fmt.Errorf("unsupported import query '%s'", moduleURL.Fragment)
+	}
+
+	switch path.Ext(moduleURL.Path) {
+	case ".json":
+		var v any
+		if err := json.Unmarshal([]byte(content), &v); err != nil {
+			return "", err
+		}
+		// JSON.parse keeps "__proto__" keys as own properties, unlike object literal
+		return "module.default = JSON.parse(" + jsString(content) + ");", nil
+	case ".css":
+		return "module.default = {css: " + jsString(content) + ", toString() { return this.css }};", nil
+	case ".svg":
+		return svgComponent(content)
+	}
+	return "", fmt.Errorf("unsupported asset '%s'", moduleURL.Path)
+}
+
+func jsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// transformModule returns JS code of module source - converted asset or transpiled script.
+func (e *Engine) transformModule(moduleURL *url.URL, content string) (string, *SourceMap, error) {
+	if isAssetModule(moduleURL) {
+		jsCode, err := assetModule(moduleURL, content)
+		return jsCode, nil, err
+	}
+	return e.transpile(moduleURL.String(), content)
+}
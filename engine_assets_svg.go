@@ -0,0 +1,68 @@
+package wax
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// svgComponentCode renders root <svg> element with its attributes merged with props.
+// Props with names which are not valid attribute names are skipped. Markup inside root element is written as is.
+const svgComponentCode = `const attrs = %s, inner = %s;
+const validName = /^[A-Za-z_:][-A-Za-z0-9_:.]*$/;
+const escape = (v) => String(v).replace(/&/g, "&amp;").replace(/"/g, "&quot;").replace(/</g, "&lt;");
+module.default = function (props) {
+    let tag = "<svg";
+    const all = Object.assign({}, attrs, props);
+    for (const name in all) {
+        const value = all[name];
+        if (name === "children" || value === undefined || value === null || value === false || !validName.test(name)) continue;
+        tag += " " + (name === "className" ? "class" : name) + (value === true ? "" : "=\"" + escape(value) + "\"");
+    }
+    return wax.Raw(tag + ">" + inner + "</svg>");
+};`
+
+// svgComponent converts SVG file to module with component as default export.
+// File is parsed here, once per compiled module.
+func svgComponent(content string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			return "", errors.New("no <svg> element")
+		}
+		if err != nil {
+			return "", err
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root.Name.Local != "svg" {
+			return "", errors.New("root element is not <svg>")
+		}
+
+		attrs := new(strings.Builder)
+		attrs.WriteString("{")
+		for i, attr := range root.Attr {
+			name := attr.Name.Local
+			if attr.Name.Space != "" {
+				name = attr.Name.Space + ":" + name
+			}
+			if i > 0 {
+				attrs.WriteString(", ")
+			}
+			attrs.WriteString(jsString(name) + ": " + jsString(attr.Value))
+		}
+		attrs.WriteString("}")
+
+		inner := ""
+		start := int(decoder.InputOffset())
+		if end := strings.LastIndex(content, "</svg"); end > start {
+			inner = strings.TrimSpace(content[start:end])
+		}
+		return fmt.Sprintf(svgComponentCode, attrs.String(), jsString(inner)), nil
+	}
+}
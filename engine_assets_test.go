@@ -0,0 +1,127 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_JSONModule(t *testing.T) {
+	fs := fstest.MapFS{
+		"Menu.jsx": &fstest.MapFile{Data: []byte(`
+            import menu from "./data/menu.json"
+            export function Menu() { return <ul>{menu.items.map(i => <li>{i}</li>)}</ul> }`)},
+		"data/menu.json": &fstest.MapFile{Data: []byte(`{"items": ["home", "blog"]}`), ModTime: time.Unix(1, 0)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs))
+	render := func() (string, error) {
+		buf := bytes.NewBufferString("")
+		err := engine.Render(buf, "Menu", nil)
+		return buf.String(), err
+	}
+
+	out, err := render()
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "menu", "<ul><li>home</li><li>blog</li></ul>", out)
+
+	t.Run("hot_reload", func(t *testing.T) {
+		fs["data/menu.json"] = &fstest.MapFile{Data: []byte(`{"items": ["about"]}`), ModTime: time.Unix(2, 0)}
+		out, err := render()
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "menu", "<ul><li>about</li></ul>", out)
+	})
+
+	t.Run("proto_key", func(t *testing.T) {
+		fs["data/menu.json"] = &fstest.MapFile{Data: []byte(`{"__proto__": {"items": ["proto"]}, "items": ["own"]}`), ModTime: time.Unix(4, 0)}
+		fs["Proto.jsx"] = &fstest.MapFile{Data: []byte(`
+            import menu from "./data/menu.json"
+            export function Proto() { return <p>{Object.keys(menu).join(",")} {menu.__proto__.items[0]} {String(Object.getPrototypeOf(menu) === Object.prototype)}</p> }`)}
+		defer delete(fs, "Proto.jsx")
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "Proto", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "proto", "<p>__proto__,items proto true</p>", buf.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fs["data/menu.json"] = &fstest.MapFile{Data: []byte(`{"items": [`), ModTime: time.Unix(3, 0)}
+		_, err := render()
+		var waxError wax.Error
+		if !errors.As(err, &waxError) || !strings.HasSuffix(waxError.File.Path, "menu.json") {
+			t.Errorf("expected error of menu.json, got %v", err)
+		}
+	})
+}
+
+func Test_Engine_TextModules(t *testing.T) {
+	fs := fstest.MapFS{
+		"Card.jsx": &fstest.MapFile{Data: []byte(`
+            import styles from "./card.css"
+            import source from "./card.css?raw"
+            import tpl from "./snippet.html?raw"
+            export function Card() {
+                return <div><style>{wax.Raw(styles)}</style>{wax.Raw(tpl)}<pre>{String(source === styles.css)}</pre></div>
+            }`)},
+		"card.css":     &fstest.MapFile{Data: []byte(`b{color:red}`)},
+		"snippet.html": &fstest.MapFile{Data: []byte(`<i>hi</i>`)},
+	}
+	expected := "<div><style>b{color:red}</style><i>hi</i><pre>true</pre></div>"
+
+	buf := bytes.NewBufferString("")
+	if err := wax.New(wax.NewFsViewResolver(fs)).Render(buf, "Card", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "card", expected, buf.String())
+
+	t.Run("bundle", func(t *testing.T) {
+		bundle := bytes.NewBuffer(nil)
+		if err := wax.New(wax.NewFsViewResolver(fs)).WriteBundle(context.Background(), bundle); err != nil {
+			t.Fatal(err)
+		}
+		resolver, err := wax.NewBundleViewResolver(bundle.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := bytes.NewBufferString("")
+		if err := wax.New(resolver).Render(buf, "Card", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "bundle", expected, buf.String())
+	})
+}
+
+func Test_Engine_SVGModule(t *testing.T) {
+	fs := fstest.MapFS{
+		"Icons.jsx": &fstest.MapFile{Data: []byte(`
+            import Check from "./icons/check.svg"
+            export function Icons() {
+                const invalid = {"onload=alert(1) x": "1", "a b": "2", "><script>": "3", "1a": "4"}
+                return <p><Check/><Check class="icon" width={16} aria-label={'a"b'}/>{Check(invalid)}</p>
+            }`)},
+		"icons/check.svg": &fstest.MapFile{Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<!-- check icon -->
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="24" height="24">
+    <path d="M1,2L3,4"/>
+</svg>`)},
+	}
+
+	buf := bytes.NewBufferString("")
+	if err := wax.New(wax.NewFsViewResolver(fs)).Render(buf, "Icons", nil); err != nil {
+		t.Fatal(err)
+	}
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%s" height="24"%s><path d="M1,2L3,4"/></svg>`
+	expected := "<p>" + fmt.Sprintf(svg, "24", "") + fmt.Sprintf(svg, "16", ` class="icon" aria-label="a&quot;b"`) + fmt.Sprintf(svg, "24", "") + "</p>"
+	compareHTML(t, "icons", expected, buf.String())
+}
@@ -0,0 +1,150 @@
+package wax
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// Async runs fn in its own goroutine and returns Promise settled with fn result.
+// Call it from Go functions used by views, with render context they get (see WithGlobalObject).
+// Loaders started this way run in parallel, the view subtree waiting for a Promise is written when it settles.
+func Async(ctx context.Context, fn func() (any, error)) *goja.Promise {
+	loop, ok := ctx.Value(eventLoopKey{}).(*eventLoop)
+	if !ok {
+		panic("wax.Async called outside of render")
+	}
+	return loop.async(fn)
+}
+
+type eventLoopKey struct{}
+
+// eventLoop settles promises of Async calls on runtime goroutine.
+type eventLoop struct {
+	ctx     context.Context
+	vm      *goja.Runtime
+	noop    goja.Callable
+	pending int
+
+	mu      sync.Mutex
+	settled []func()
+	signal  chan struct{}
+}
+
+func newEventLoop(ctx context.Context, vm *goja.Runtime) *eventLoop {
+	noop, _ := goja.AssertFunction(vm.ToValue(func() {}))
+	l := &eventLoop{
+		vm:     vm,
+		noop:   noop,
+		signal: make(chan struct{}, 1),
+	}
+	l.ctx = context.WithValue(ctx, eventLoopKey{}, l)
+	return l
+}
+
+func (l *eventLoop) async(fn func() (any, error)) *goja.Promise {
+	promise, resolve, reject := l.vm.NewPromise()
+	l.pending++
+	go func() {
+		v, err := fn()
+		l.mu.Lock()
+		l.settled = append(l.settled, func() {
+			if err != nil {
+				reject(l.vm.NewGoError(err))
+			} else {
+				resolve(v)
+			}
+		})
+		l.mu.Unlock()
+		select {
+		case l.signal <- struct{}{}:
+		default:
+		}
+	}()
+	return promise
+}
+
+var errNeverSettled = errors.New("promise will never be settled")
+
+// await runs JS jobs and settles finished Async calls until p is settled.
+// Must be called outside of JS code.
+func (l *eventLoop) await(p *goja.Promise) error {
+	// returning from top level call runs promise jobs
+	if _, err := l.noop(goja.Undefined()); err != nil {
+		return err
+	}
+	for p.State() == goja.PromiseStatePending {
+		if l.pending == 0 {
+			return errNeverSettled
+		}
+		select {
+		case <-l.signal:
+		case <-l.ctx.Done():
+			return context.Cause(l.ctx)
+		}
+		l.mu.Lock()
+		settled := l.settled
+		l.settled = nil
+		l.mu.Unlock()
+		for _, settle := range settled {
+			l.pending--
+			settle()
+		}
+		if _, err := l.noop(goja.Undefined()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var reflectTypePromise = reflect.TypeOf((*goja.Promise)(nil))
+
+// asyncPart is either a hole left by pending promise or content written after it.
+type asyncPart struct {
+	promise *goja.Promise
+	text    *bytes.Buffer
+}
+
+func (w *waxWriter) writePromise(p *goja.Promise) {
+	switch p.State() {
+	case goja.PromiseStateFulfilled:
+		w.process(p.Result(), w.vm)
+	case goja.PromiseStateRejected:
+		panic(p.Result())
+	default:
+		if w.loop == nil {
+			panic(w.vm.NewTypeError("pending promise can not be written here"))
+		}
+		text := &asyncPart{text: &bytes.Buffer{}}
+		w.parts = append(w.parts[:w.insertAt], append([]*asyncPart{{promise: p}, text}, w.parts[w.insertAt:]...)...)
+		w.insertAt += 2
+		w.buf = text.text
+	}
+}
+
+// settle writes results of pending promises in place of their holes.
+// Must be called outside of JS code.
+func (w *waxWriter) settle() {
+	for len(w.parts) > 0 && w.err == nil {
+		head := w.parts[0]
+		if head.promise == nil {
+			w.parts, w.buf = w.parts[1:], nil
+			w.WriteRaw(head.text.String())
+			continue
+		}
+		if head.promise.State() == goja.PromiseStatePending {
+			w.buf = nil
+			w.Flush()
+		}
+		if err := w.loop.await(head.promise); err != nil {
+			panic(w.vm.NewGoError(err))
+		}
+		w.parts, w.buf, w.insertAt = w.parts[1:], nil, 0
+		w.writePromise(head.promise)
+	}
+	w.buf, w.insertAt = nil, 0
+}
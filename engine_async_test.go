@@ -0,0 +1,102 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Async(t *testing.T) {
+	fs := fstest.MapFS{
+		"AsyncView.jsx": &fstest.MapFile{Data: []byte(`
+            export async function AsyncView() {
+                const user = await data.Load("user", 20)
+                return <h1>{user}</h1>
+            }`)},
+		"Parallel.jsx": &fstest.MapFile{Data: []byte(`
+            async function Slow(p) {
+                const value = await p.value
+                return <i>{value}</i>
+            }
+            export function Parallel() {
+                const a = data.Load("a", 50)
+                const b = data.Load("b", 10)
+                return <div><b>start</b><Slow value={a}/>{b}<Slow value={Promise.resolve("c")}/><b>end</b></div>
+            }`)},
+		"Rejected.jsx": &fstest.MapFile{Data: []byte(`
+            export function Rejected() {
+                return <div>{data.Fail()}</div>
+            }`)},
+		"Caught.jsx": &fstest.MapFile{Data: []byte(`
+            export async function Caught() {
+                try {
+                    await data.Fail()
+                } catch (e) {
+                    return <div>caught</div>
+                }
+            }`)},
+	}
+	errFailed := errors.New("failed to load")
+	services := map[string]any{
+		"Load": func(ctx context.Context, v string, delayMs int) any {
+			return wax.Async(ctx, func() (any, error) {
+				time.Sleep(time.Duration(delayMs) * time.Millisecond)
+				return v, nil
+			})
+		},
+		"Fail": func(ctx context.Context) any {
+			return wax.Async(ctx, func() (any, error) {
+				return nil, errFailed
+			})
+		},
+	}
+
+	checks := []struct {
+		view     string
+		expected string
+	}{
+		{view: "AsyncView", expected: "<h1>user</h1>"},
+		{view: "Parallel", expected: "<div><b>start</b><i>a</i>b<i>c</i><b>end</b></div>"},
+		{view: "Caught", expected: "<div>caught</div>"},
+	}
+	for name, pooled := range map[string]bool{"new_runtime": false, "pooled_runtime": true} {
+		options := []wax.Option{wax.WithGlobalObject("data", services)}
+		if pooled {
+			options = append(options, wax.WithRuntimePool(1))
+		}
+		engine := wax.New(wax.NewFsViewResolver(fs), options...)
+
+		t.Run(name, func(t *testing.T) {
+			for _, check := range checks {
+				t.Run(check.view, func(t *testing.T) {
+					buf := bytes.NewBufferString("")
+					if err := engine.Render(buf, check.view, nil); err != nil {
+						t.Fatal(err)
+					}
+					compareHTML(t, check.view, check.expected, buf.String())
+				})
+			}
+
+			t.Run("Rejected", func(t *testing.T) {
+				err := engine.Render(bytes.NewBufferString(""), "Rejected", nil)
+				if !errors.Is(err, errFailed) {
+					t.Errorf("expected rejection error, got %v", err)
+				}
+			})
+
+			t.Run("deadline", func(t *testing.T) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+				defer cancel()
+				err := engine.RenderContext(ctx, bytes.NewBufferString(""), "Parallel", nil)
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("expected deadline error, got %v", err)
+				}
+			})
+		})
+	}
+}
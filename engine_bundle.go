@@ -0,0 +1,190 @@
+package wax
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// WriteBundle transpiles and validates modules of view resolver and writes them as bundle
+// for NewBundleViewResolver. View resolver must implement ModuleLister.
+// Bundle has listed modules, global scripts and all modules they import. Layered view resolver is not supported.
+// Nothing is written when any module fails.
+func (e *Engine) WriteBundle(ctx context.Context, out io.Writer) error {
+	lister, ok := e.viewResolver.(ModuleLister)
+	if !ok {
+		return errors.New("view resolver can not list modules")
+	}
+	if _, layered := e.viewResolver.(*viewResolverLayered); layered {
+		// bundle has one file for each path, overridden files and "super:" imports can't be resolved from it
+		return errors.New("layered view resolver can not be bundled")
+	}
+	modules, err := lister.ListModules()
+	if err != nil {
+		return err
+	}
+	for _, script := range e.globalScripts {
+		moduleURL, err := e.viewResolver.ResolveModuleFile(ModuleMeta{URL: rootModuleURL}, script)
+		if err != nil {
+			return err
+		}
+		modules = append(modules, moduleURL)
+	}
+
+	var (
+		entries []bundleEntry
+		names   = make(map[string]bundleEntry)
+		visited = make(map[string]bool)
+		errs    []error
+	)
+	for len(modules) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		moduleURL := modules[0]
+		modules = modules[1:]
+		if visited[moduleURL.String()] {
+			continue
+		}
+		visited[moduleURL.String()] = true
+
+		entry, imports, err := e.bundleModule(moduleURL)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, importPath := range imports {
+			imported, err := e.resolveModule(e.viewResolver, ModuleMeta{URL: moduleURL}, importPath)
+			if err != nil {
+				errs = append(errs, Error{File: *moduleURL, Phase: PhaseLoading, Err: err})
+				continue
+			}
+			modules = append(modules, imported)
+		}
+		if entry == nil {
+			continue
+		}
+		if other, ok := names[entry.name]; ok {
+			if other.source != entry.source {
+				errs = append(errs, Error{File: *moduleURL, Phase: PhaseLoading, Err: fmt.Errorf("'%s' is imported both as script and as text", entry.name)})
+			}
+			continue
+		}
+		names[entry.name] = *entry
+		entries = append(entries, *entry)
+	}
+	if fsResolver, ok := e.viewResolver.(*viewResolverFS); ok {
+		// tsconfig.json with its `extends` is read by bundle resolver given WithTSConfig
+		files, err := fsResolver.tsConfigFiles()
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, file := range files {
+			if _, ok := names[file]; ok {
+				continue
+			}
+			content, err := fs.ReadFile(fsResolver.fs, file)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			entries = append(entries, bundleEntry{name: file, code: string(content), source: true})
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	archive := zip.NewWriter(out)
+	created := time.Now()
+	create := func(name string) (io.Writer, error) {
+		return archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: created})
+	}
+	for _, entry := range entries {
+		w, err := create(entry.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, entry.code); err != nil {
+			return err
+		}
+		if entry.sourceMap == nil {
+			continue
+		}
+		w, err = create(entry.name + bundleSourceMapExt)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(w).Encode(entry.sourceMap); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+type bundleEntry struct {
+	name      string
+	code      string
+	sourceMap *SourceMap
+	// source is set when code is content of the file, for assets and "?raw" imports
+	source bool
+}
+
+// bundleModule returns bundle entry of module and its imports.
+// Registered modules have no entry, but imports of virtual modules are bundled.
+func (e *Engine) bundleModule(moduleURL *url.URL) (*bundleEntry, []string, error) {
+	if m, ok := e.registeredModule(moduleURL); ok {
+		if isNativeModule(m) {
+			return nil, nil, nil
+		}
+		code, _, err := e.transpile(moduleURL.String(), m.source)
+		if err != nil {
+			return nil, nil, Error{File: *moduleURL, Phase: PhaseLoading, Err: err}
+		}
+		return nil, findImports(code), nil
+	}
+
+	content, err := e.viewResolver.GetContent(*moduleURL)
+	if err != nil {
+		return nil, nil, Error{File: *moduleURL, Phase: PhaseLoading, Err: err}
+	}
+	code, sourceMap, err := e.transformModule(moduleURL, content)
+	if err != nil {
+		return nil, nil, Error{File: *moduleURL, Phase: PhaseLoading, Err: err}
+	}
+	if _, err := goja.Compile(moduleURL.String(), wrapModule(code, moduleURL.String()), true); err != nil {
+		return nil, nil, Error{File: *moduleURL, Phase: PhaseCompilation, Err: err}
+	}
+	entry := &bundleEntry{
+		name:      strings.TrimPrefix(moduleURL.Path, "/"),
+		code:      code,
+		sourceMap: sourceMap,
+	}
+	if isAssetModule(moduleURL) {
+		// assets are converted when imported, the way depends on import query
+		entry.code, entry.source = content, true
+		return entry, nil, nil
+	}
+	return entry, append(findImports(code), findDynamicImports(code)...), nil
+}
+
+// dynamicImportCall matches dynamic imports of literal paths, other dynamic imports can't be bundled.
+var dynamicImportCall = regexp.MustCompile(`module\.do_import_dynamic\(\s*["'\x60]([^"'\x60$]*)["'\x60]\s*\)`)
+
+func findDynamicImports(jsCode string) []string {
+	var result []string
+	for _, match := range dynamicImportCall.FindAllStringSubmatch(jsCode, -1) {
+		result = append(result, match[1])
+	}
+	return result
+}
@@ -0,0 +1,43 @@
+//go:build wax_no_treesitter
+
+package wax_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/michal-laskowski/wax"
+)
+
+// Test_Engine_BundleWithoutTranspiler renders hand-written bundle, tests of views need tree-sitter.
+func Test_Engine_BundleWithoutTranspiler(t *testing.T) {
+	bundle := bytes.NewBuffer(nil)
+	archive := zip.NewWriter(bundle)
+	for name, code := range map[string]string{
+		"Home.jsx": "module.exports.Home = Home; const {upper} = module.do_import('./lib/text.ts').exports;" +
+			"function Home(p) { return wax.Sub(w => w.WriteHTML(`<main>`).WriteValue(upper(p.title)).WriteHTML(`</main>`)) }",
+		"lib/text.ts": "module.exports.upper = upper; function upper(s) { return s.toUpperCase() }",
+	} {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(code)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := wax.NewBundleViewResolver(bundle.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBufferString("")
+	if err := wax.New(resolver).Render(buf, "Home", map[string]any{"title": "t"}); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "bundle", "<main>T</main>", buf.String())
+}
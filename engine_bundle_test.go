@@ -0,0 +1,94 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Bundle(t *testing.T) {
+	fs := fstest.MapFS{
+		"Home.tsx": &fstest.MapFile{Data: []byte(`
+            import {Button} from "./parts/Button.tsx"
+            import {upper} from "./lib/text.ts"
+            export async function loader(p: {title: string}) {
+                const {suffix} = await import("./lib/suffix.ts")
+                return {title: upper(p.title) + suffix}
+            }
+            export function Home(p: {title: string}) { return <main><Button/>{p.title}</main> }`)},
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            export function Button() { return <button>ok</button> }`)},
+		"lib/text.ts": &fstest.MapFile{Data: []byte(`
+            export function upper(s: string): string { return s.toUpperCase() }`)},
+		"lib/suffix.ts": &fstest.MapFile{Data: []byte(`
+            export const suffix = "!" as string`)},
+	}
+
+	bundle := bytes.NewBuffer(nil)
+	if err := wax.New(wax.NewFsViewResolver(fs)).WriteBundle(context.Background(), bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := wax.NewBundleViewResolver(bundle.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resolver.(wax.TranspiledContentProvider); !ok {
+		t.Fatal("bundle resolver must provide transpiled content")
+	}
+	buf := bytes.NewBufferString("")
+	if err := wax.New(resolver).Render(buf, "Home", map[string]any{"title": "t"}); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "bundle", "<main><button>ok</button>T!</main>", buf.String())
+
+	t.Run("invalid_views", func(t *testing.T) {
+		fs["Broken.tsx"] = &fstest.MapFile{Data: []byte(`export function Broken() { return <div> }`)}
+		defer delete(fs, "Broken.tsx")
+
+		out := bytes.NewBuffer(nil)
+		err := wax.New(wax.NewFsViewResolver(fs)).WriteBundle(context.Background(), out)
+		var waxError wax.Error
+		if !errors.As(err, &waxError) || !strings.HasSuffix(waxError.File.Path, "Broken.tsx") {
+			t.Fatalf("expected error of Broken.tsx, got %v", err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("bundle written despite errors")
+		}
+	})
+}
+
+func Test_Engine_BundleTSConfig(t *testing.T) {
+	fs := fstest.MapFS{
+		"tsconfig.base.json": &fstest.MapFile{Data: []byte(`{"compilerOptions": {"paths": {"@/*": ["./*"]}}}`)},
+		"tsconfig.json": &fstest.MapFile{Data: []byte(`{
+            // aliases are in base config
+            "extends": "./tsconfig.base.json"
+        }`)},
+		"Home.tsx": &fstest.MapFile{Data: []byte(`
+            import {Button} from "@/parts/Button"
+            export function Home() { return <main><Button/></main> }`)},
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            export function Button() { return <button>ok</button> }`)},
+	}
+
+	bundle := bytes.NewBuffer(nil)
+	if err := wax.New(wax.NewFsViewResolver(fs, wax.WithTSConfig("tsconfig.json"))).WriteBundle(context.Background(), bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver, err := wax.NewBundleViewResolver(bundle.Bytes(), wax.WithTSConfig("tsconfig.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBufferString("")
+	if err := wax.New(resolver).Render(buf, "Home", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "bundle", "<main><button>ok</button></main>", buf.String())
+}
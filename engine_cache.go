@@ -0,0 +1,149 @@
+package wax
+
+import (
+	"container/list"
+	"net/url"
+	"sync"
+)
+
+// ProgramCache stores compiled modules by module URL.
+type ProgramCache interface {
+	Get(moduleURL *url.URL) (*CompiledModule, bool)
+	// Peek returns module like Get, but does not count hit or miss and does not mark module as used.
+	Peek(moduleURL *url.URL) (*CompiledModule, bool)
+	Put(moduleURL *url.URL, module *CompiledModule)
+	// Invalidate drops all versions of the module, whatever URL query is.
+	Invalidate(moduleURL *url.URL)
+	Purge()
+	Stats() CacheStats
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+const DefaultProgramCacheSize = 1024
+
+// WithProgramCache replaces default LRU cache of compiled modules.
+func WithProgramCache(cache ProgramCache) Option {
+	return func(e *Engine) {
+		e.cache = cache
+	}
+}
+
+// Cache returns cache of compiled modules used by engine.
+func (e *Engine) Cache() ProgramCache {
+	return e.cache
+}
+
+// NewLRUProgramCache returns cache keeping up to size recently used modules.
+// Putting new version of a module (same URL, other query) drops the old one.
+func NewLRUProgramCache(size int) ProgramCache {
+	return &lruProgramCache{
+		size:     size,
+		items:    make(map[string]*list.Element),
+		versions: make(map[string]string),
+		order:    list.New(),
+	}
+}
+
+type lruProgramCache struct {
+	mu       sync.Mutex
+	size     int
+	items    map[string]*list.Element
+	versions map[string]string
+	order    *list.List
+	stats    CacheStats
+}
+
+type lruEntry struct {
+	key    string
+	module string
+	value  *CompiledModule
+}
+
+func (c *lruProgramCache) Get(moduleURL *url.URL) (*CompiledModule, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[moduleURL.String()]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(item)
+	return item.Value.(*lruEntry).value, true
+}
+
+func (c *lruProgramCache) Peek(moduleURL *url.URL) (*CompiledModule, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[moduleURL.String()]
+	if !ok {
+		return nil, false
+	}
+	return item.Value.(*lruEntry).value, true
+}
+
+func (c *lruProgramCache) Put(moduleURL *url.URL, module *CompiledModule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key, name := moduleURL.String(), moduleName(moduleURL)
+	if item, ok := c.items[key]; ok {
+		item.Value.(*lruEntry).value = module
+		c.order.MoveToFront(item)
+		return
+	}
+	if stale, ok := c.versions[name]; ok {
+		c.remove(c.items[stale])
+		c.stats.Evictions++
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, module: name, value: module})
+	c.versions[name] = key
+	for c.size > 0 && c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *lruProgramCache) Invalidate(moduleURL *url.URL) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if key, ok := c.versions[moduleName(moduleURL)]; ok {
+		c.remove(c.items[key])
+	}
+}
+
+func (c *lruProgramCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[string]*list.Element)
+	c.versions = make(map[string]string)
+	c.order.Init()
+}
+
+func (c *lruProgramCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+func (c *lruProgramCache) remove(item *list.Element) {
+	entry := item.Value.(*lruEntry)
+	c.order.Remove(item)
+	delete(c.items, entry.key)
+	delete(c.versions, entry.module)
+}
+
+// moduleName is module URL without query - the same for all versions of a file.
+// Fragment is kept, it tells how file is imported (see splitImportQuery).
+func moduleName(moduleURL *url.URL) string {
+	u := *moduleURL
+	u.RawQuery, u.ForceQuery = "", false
+	return u.String()
+}
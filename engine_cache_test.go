@@ -0,0 +1,81 @@
+package wax_test
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_LRUProgramCache(t *testing.T) {
+	moduleURL := func(s string) *url.URL {
+		u, _ := url.Parse(s)
+		return u
+	}
+	a1, a2 := moduleURL("file:///a.jsx?ts=1"), moduleURL("file:///a.jsx?ts=2")
+	b, c := moduleURL("file:///b.jsx?ts=1"), moduleURL("file:///c.jsx?ts=1")
+
+	cache := wax.NewLRUProgramCache(2)
+	cache.Put(a1, &wax.CompiledModule{})
+	cache.Put(a2, &wax.CompiledModule{})
+	if _, ok := cache.Get(a1); ok {
+		t.Error("stale version of module is still cached")
+	}
+	if _, ok := cache.Get(a2); !ok {
+		t.Error("new version of module is not cached")
+	}
+
+	cache.Put(b, &wax.CompiledModule{})
+	cache.Get(a2)
+	// peek counts neither hit nor miss and keeps b least recently used
+	if _, ok := cache.Peek(b); !ok {
+		t.Error("peek does not return cached module")
+	}
+	cache.Peek(a1)
+	cache.Put(c, &wax.CompiledModule{})
+	if _, ok := cache.Get(b); ok {
+		t.Error("least recently used module is still cached")
+	}
+
+	expected := wax.CacheStats{Hits: 2, Misses: 2, Evictions: 2, Size: 2}
+	if stats := cache.Stats(); stats != expected {
+		t.Errorf("invalid stats > \n\tgot      : %+v\n\texpected : %+v", stats, expected)
+	}
+
+	cache.Invalidate(moduleURL("file:///a.jsx"))
+	if _, ok := cache.Get(a2); ok {
+		t.Error("invalidated module is still cached")
+	}
+	cache.Purge()
+	if size := cache.Stats().Size; size != 0 {
+		t.Errorf("cache not empty after purge, size %d", size)
+	}
+}
+
+func Test_Engine_Cache_HotReload(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`export function View() { return <b>v1</b> }`), ModTime: time.Unix(1, 0)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs))
+	render := func(expected string) {
+		t.Helper()
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "View", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, expected, expected, buf.String())
+	}
+
+	render("<b>v1</b>")
+	render("<b>v1</b>")
+	fs["View.jsx"] = &fstest.MapFile{Data: []byte(`export function View() { return <b>v2</b> }`), ModTime: time.Unix(2, 0)}
+	render("<b>v2</b>")
+
+	stats := engine.Cache().Stats()
+	if stats.Size != 1 || stats.Hits != 1 {
+		t.Errorf("expected only current version to be cached after one hit, got %+v", stats)
+	}
+}
@@ -0,0 +1,184 @@
+package wax
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"reflect"
+
+	"github.com/dop251/goja"
+)
+
+// watchContext interrupts vm when ctx is done. Returned func must be called before vm is reused.
+func watchContext(ctx context.Context, vm *goja.Runtime) func() {
+	done := ctx.Done()
+	if done == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-done:
+			vm.Interrupt(context.Cause(ctx))
+		case <-stop:
+		}
+	}()
+	return func() {
+		close(stop)
+		<-exited
+	}
+}
+
+// interruptionError maps errors of interrupted execution to Error.
+func interruptionError(ctx context.Context, file url.URL, err error) error {
+	var overflow *goja.StackOverflowError
+	if errors.As(err, &overflow) {
+		return Error{
+			File:  file,
+			Stack: overflow.Error(),
+			Phase: PhaseLimit,
+			Err:   ErrCallStackExceeded,
+		}
+	}
+
+	var (
+		interrupted *goja.InterruptedError
+		cause       error
+		stack       string
+	)
+	switch {
+	case errors.As(err, &interrupted):
+		cause, stack = interrupted.Unwrap(), interrupted.String()
+	case ctx.Err() != nil && errors.Is(err, context.Cause(ctx)):
+		// context was done while waiting for promises
+		cause, stack = context.Cause(ctx), err.Error()
+	default:
+		return err
+	}
+	switch {
+	case isLimitError(cause):
+		return Error{
+			File:  file,
+			Stack: stack,
+			Phase: PhaseLimit,
+			Err:   cause,
+		}
+	case ctx.Err() != nil:
+		return Error{
+			File:  file,
+			Stack: stack,
+			Phase: PhaseExec,
+			Err:   ctx.Err(),
+		}
+	}
+	return err
+}
+
+// try works like goja.Runtime.Try, but also returns uncatchable errors (interrupts, stack overflow).
+func try(vm *goja.Runtime, f func()) (err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			switch x := x.(type) {
+			case *goja.InterruptedError:
+				err = x
+			case *goja.StackOverflowError:
+				err = x
+			default:
+				panic(x)
+			}
+		}
+	}()
+	if ex := vm.Try(f); ex != nil {
+		return ex
+	}
+	return nil
+}
+
+var reflectTypeContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// bindContext makes Go functions taking context.Context as first argument callable from JS.
+// Render context is passed as that argument. Functions are bound when given directly or as values
+// of map[string]any and elements of []any, at any depth - maps and slices having them are copied.
+// Methods and fields of structs and other maps or slices are not bound.
+func (c *waxJSObj) bindContext(v any) any {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		if !hasContextFunc(v) {
+			return v
+		}
+		result := make(map[string]any, len(v))
+		for k, mv := range v {
+			result[k] = c.bindContext(mv)
+		}
+		return result
+	case []any:
+		if !hasContextFunc(v) {
+			return v
+		}
+		result := make([]any, len(v))
+		for i, sv := range v {
+			result[i] = c.bindContext(sv)
+		}
+		return result
+	}
+
+	fn := reflect.ValueOf(v)
+	if !isContextFunc(fn.Type()) {
+		return v
+	}
+	fnType := fn.Type()
+	in := make([]reflect.Type, 0, fnType.NumIn()-1)
+	for i := 1; i < fnType.NumIn(); i++ {
+		in = append(in, fnType.In(i))
+	}
+	out := make([]reflect.Type, 0, fnType.NumOut())
+	for i := 0; i < fnType.NumOut(); i++ {
+		out = append(out, fnType.Out(i))
+	}
+	bound := reflect.MakeFunc(reflect.FuncOf(in, out, fnType.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{reflect.ValueOf(c.ctx())}, args...)
+		if fnType.IsVariadic() {
+			return fn.CallSlice(args)
+		}
+		return fn.Call(args)
+	})
+	return bound.Interface()
+}
+
+func (c *waxJSObj) ctx() context.Context {
+	if c.context == nil || c.context.ctx == nil {
+		return context.Background()
+	}
+	return c.context.ctx
+}
+
+func isContextFunc(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.NumIn() > 0 && t.In(0) == reflectTypeContext
+}
+
+// hasContextFunc reports whether v is or has function to bind.
+func hasContextFunc(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case map[string]any:
+		for _, mv := range v {
+			if hasContextFunc(mv) {
+				return true
+			}
+		}
+		return false
+	case []any:
+		for _, sv := range v {
+			if hasContextFunc(sv) {
+				return true
+			}
+		}
+		return false
+	}
+	return isContextFunc(reflect.TypeOf(v))
+}
@@ -0,0 +1,114 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/michal-laskowski/wax"
+)
+
+type ctxKey string
+
+func Test_Engine_RenderContext(t *testing.T) {
+	fs := fstest.MapFS{
+		"Loop.jsx": &fstest.MapFile{Data: []byte(`
+            export function Loop() {
+                while (true) {}
+                return <div>never</div>
+            }`)},
+		"User.jsx": &fstest.MapFile{Data: []byte(`
+            export function User() {
+                return <div>{services.CurrentUser("id")} - {CurrentUser("name")}</div>
+            }`)},
+	}
+	currentUser := func(ctx context.Context, field string) string {
+		return field + ":" + ctx.Value(ctxKey("user")).(string)
+	}
+
+	for name, pooled := range map[string]bool{"new_runtime": false, "pooled_runtime": true} {
+		t.Run(name, func(t *testing.T) {
+			testRenderContext(t, fs, currentUser, pooled)
+		})
+	}
+}
+
+func testRenderContext(t *testing.T, fs fstest.MapFS, currentUser any, pooled bool) {
+	options := []wax.Option{
+		wax.WithGlobalObject("services", map[string]any{"CurrentUser": currentUser}),
+	}
+	if pooled {
+		options = append(options, wax.WithRuntimePool(1))
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), options...)
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := engine.RenderContext(ctx, bytes.NewBufferString(""), "Loop", nil)
+		var waxError wax.Error
+		if !errors.As(err, &waxError) {
+			t.Fatalf("expected wax.Error, got %v", err)
+		}
+		if waxError.Phase != wax.PhaseExec {
+			t.Errorf("invalid phase %s", waxError.Phase)
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected to wrap context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("canceled_before_render", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		buf := bytes.NewBufferString("")
+		err := engine.RenderContext(ctx, buf, "User", nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected to wrap context.Canceled, got %v", err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected no output, got %s", buf.String())
+		}
+	})
+
+	t.Run("go_functions_get_context", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), ctxKey("user"), "john")
+		buf := bytes.NewBufferString("")
+		err := engine.RenderWithContext(ctx, buf, "User", wax.RunBinding{
+			ViewResolver: wax.NewFsViewResolver(fs),
+			Globals:      map[string]any{"CurrentUser": currentUser},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "go_functions_get_context", "<div>id:john - name:john</div>", buf.String())
+	})
+}
+
+func Test_Engine_RenderContextNestedFunctions(t *testing.T) {
+	fs := fstest.MapFS{
+		"User.jsx": &fstest.MapFile{Data: []byte(`
+            export function User() {
+                return <div>{services.users.Current()} - {services.list[0]()} - {services.list[1].Get()}</div>
+            }`)},
+	}
+	user := func(ctx context.Context) string {
+		return ctx.Value(ctxKey("user")).(string)
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), wax.WithGlobalObject("services", map[string]any{
+		"users": map[string]any{"Current": user},
+		"list":  []any{user, map[string]any{"Get": user}},
+	}))
+
+	ctx := context.WithValue(context.Background(), ctxKey("user"), "john")
+	buf := bytes.NewBufferString("")
+	if err := engine.RenderContext(ctx, buf, "User", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "nested", "<div>john - john - john</div>", buf.String())
+}
@@ -0,0 +1,46 @@
+package wax
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// WithImportCycles allows import cycles. Module imported back while it is still loading gets exports defined so far -
+// exported functions are always there, other exports are visible through namespace import (`import * as m`) after loading.
+// Without this option cycle fails the render with PhaseLoading error.
+func WithImportCycles() Option {
+	return func(e *Engine) {
+		e.importCycles = true
+	}
+}
+
+// importCycle returns import chain from module being loaded to moduleURL, when moduleURL is in it.
+func (c *waxJSObj) importCycle(moduleURL *url.URL) []*url.URL {
+	key := moduleURL.String()
+	for i, loading := range c.loading {
+		if loading.String() == key {
+			return append(c.loading[i:len(c.loading):len(c.loading)], moduleURL)
+		}
+	}
+	return nil
+}
+
+func importCycleError(chain []*url.URL) error {
+	names := make([]string, 0, len(chain))
+	for _, u := range chain {
+		names = append(names, moduleDisplayName(u))
+	}
+	return Error{
+		File:  *chain[0],
+		Phase: PhaseLoading,
+		Err:   fmt.Errorf("import cycle: %s", strings.Join(names, " → ")),
+	}
+}
+
+func moduleDisplayName(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
@@ -0,0 +1,44 @@
+package wax_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_ImportCycles(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.tsx": &fstest.MapFile{Data: []byte(`
+            import { Tree } from "./tree/a.tsx"
+            export function View() { return <ul><Tree depth={3}/></ul> }`)},
+		"tree/a.tsx": &fstest.MapFile{Data: []byte(`
+            import { Branch } from "./b.tsx"
+            export function Tree(p) { return p.depth > 0 ? <li>a<Branch depth={p.depth - 1}/></li> : null }`)},
+		"tree/b.tsx": &fstest.MapFile{Data: []byte(`
+            import { Tree } from "./a.tsx"
+            export function Branch(p) { return p.depth > 0 ? <li>b<Tree depth={p.depth - 1}/></li> : null }`)},
+	}
+
+	t.Run("reported", func(t *testing.T) {
+		err := wax.New(wax.NewFsViewResolver(fs)).Render(bytes.NewBufferString(""), "View", nil)
+		var waxError wax.Error
+		if !errors.As(err, &waxError) || waxError.Phase != wax.PhaseLoading {
+			t.Fatalf("expected loading error, got %v", err)
+		}
+		if !strings.Contains(waxError.Error(), "tree/a.tsx → tree/b.tsx → tree/a.tsx") {
+			t.Errorf("error does not name import chain: %v", waxError)
+		}
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		if err := wax.New(wax.NewFsViewResolver(fs), wax.WithImportCycles()).Render(buf, "View", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "allowed", "<ul><li>a<li>b<li>a</li></li></li></ul>", buf.String())
+	})
+}
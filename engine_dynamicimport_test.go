@@ -0,0 +1,59 @@
+package wax_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_DynamicImport(t *testing.T) {
+	fs := fstest.MapFS{
+		"Page.jsx": &fstest.MapFile{Data: []byte(`
+            async function Widget(p) {
+                const widget = await import("./widgets/" + p.name + ".jsx")
+                return <widget.default title={p.name}/>
+            }
+            export function Page(p) {
+                return <main>{p.widgets.map(name => <Widget name={name}/>)}</main>
+            }`)},
+		"Named.jsx": &fstest.MapFile{Data: []byte(`
+            export function Named() {
+                return <p>{import("./widgets/Chart.jsx").then(m => m.label)}</p>
+            }`)},
+		"Missing.jsx": &fstest.MapFile{Data: []byte(`
+            export async function Missing() {
+                try {
+                    await import("./widgets/Nope.jsx")
+                } catch (e) {
+                    return <p>fallback</p>
+                }
+            }`)},
+		"widgets/Chart.jsx": &fstest.MapFile{Data: []byte(`
+            export const label = "chart"
+            export default function Chart(p) { return <figure>{p.title}</figure> }`)},
+		"widgets/Quote.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Quote(p) { return <blockquote>{p.title}</blockquote> }`)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs))
+
+	checks := []struct {
+		view     string
+		model    any
+		expected string
+	}{
+		{view: "Page", model: map[string]any{"widgets": []string{"Chart", "Quote"}}, expected: "<main><figure>Chart</figure><blockquote>Quote</blockquote></main>"},
+		{view: "Named", expected: "<p>chart</p>"},
+		{view: "Missing", expected: "<p>fallback</p>"},
+	}
+	for _, check := range checks {
+		t.Run(check.view, func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			if err := engine.Render(buf, check.view, check.model); err != nil {
+				t.Fatal(err)
+			}
+			compareHTML(t, check.view, check.expected, buf.String())
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package wax
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Route maps output path to view rendered into it.
+type Route struct {
+	// Path of the page, e.g. "/", "/blog/post-1" or "/feed.xml".
+	// Path without extension is written as directory index.html.
+	Path string `json:"path"`
+	View string `json:"view"`
+	// Model passed to the view. When ModelFile is set model is read from this JSON file.
+	Model     any    `json:"model,omitempty"`
+	ModelFile string `json:"modelFile,omitempty"`
+}
+
+// RouteGenerator returns routes computed at generation time, e.g. one per blog post.
+type RouteGenerator func(ctx context.Context) ([]Route, error)
+
+type GenerateOptions struct {
+	OutDir     string
+	Routes     []Route
+	Generators []RouteGenerator
+	// StaticDir content is copied to OutDir as is.
+	StaticDir string
+	// BaseURL of the site. When set sitemap.xml with all HTML pages is written.
+	BaseURL string
+	// Concurrency limits number of pages rendered at once, GOMAXPROCS by default.
+	Concurrency int
+}
+
+// Generate renders all routes into files in options.OutDir.
+// Returned error joins errors of all routes that failed.
+func (e *Engine) Generate(ctx context.Context, options GenerateOptions) error {
+	routes := append([]Route(nil), options.Routes...)
+	for _, generator := range options.Generators {
+		generated, err := generator(ctx)
+		if err != nil {
+			return err
+		}
+		routes = append(routes, generated...)
+	}
+	// routes writing the same file would overwrite each other in random order
+	files := make(map[string]Route, len(routes))
+	if options.BaseURL != "" {
+		files["sitemap.xml"] = Route{Path: "/sitemap.xml"}
+	}
+	for _, route := range routes {
+		file := routeFile(route.Path)
+		if other, ok := files[file]; ok {
+			return fmt.Errorf("routes '%s' and '%s' are written to the same file '%s'", other.Path, route.Path, file)
+		}
+		files[file] = route
+	}
+
+	if err := os.MkdirAll(options.OutDir, 0o755); err != nil {
+		return err
+	}
+	if options.StaticDir != "" {
+		if err := copyDir(options.OutDir, os.DirFS(options.StaticDir)); err != nil {
+			return err
+		}
+	}
+
+	concurrency := options.Concurrency
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+	var (
+		mu      sync.Mutex
+		errs    []error
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, concurrency)
+	)
+	for _, route := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			if err := e.generateRoute(ctx, options.OutDir, route); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("route '%s': %w", route.Path, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	if options.BaseURL != "" {
+		return writeSitemap(options.OutDir, options.BaseURL, routes)
+	}
+	return nil
+}
+
+func (e *Engine) generateRoute(ctx context.Context, outDir string, route Route) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	model := route.Model
+	if route.ModelFile != "" {
+		data, err := os.ReadFile(route.ModelFile)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(data, &model); err != nil {
+			return fmt.Errorf("model file '%s': %w", route.ModelFile, err)
+		}
+	}
+
+	outFile := filepath.Join(outDir, filepath.FromSlash(routeFile(route.Path)))
+	if err := os.MkdirAll(filepath.Dir(outFile), 0o755); err != nil {
+		return err
+	}
+	// page is rendered to temporary file and renamed when complete, failed render leaves no partial page
+	f, err := os.CreateTemp(filepath.Dir(outFile), ".wax-*.tmp")
+	if err != nil {
+		return err
+	}
+	out := bufio.NewWriter(f)
+	err = e.RenderContext(ctx, out, route.View, model)
+	if err == nil {
+		err = out.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), outFile)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// routeFile returns file path of the route, relative to output directory.
+func routeFile(routePath string) string {
+	p := path.Clean("/" + routePath)
+	if path.Ext(p) == "" {
+		p = path.Join(p, "index.html")
+	}
+	return strings.TrimPrefix(p, "/")
+}
+
+func copyDir(dst string, src fs.FS) error {
+	return fs.WalkDir(src, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, filepath.FromSlash(p))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		in, err := src.Open(p)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc string `xml:"loc"`
+}
+
+func writeSitemap(outDir string, baseURL string, routes []Route) error {
+	urlSet := sitemapURLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for _, route := range routes {
+		file := routeFile(route.Path)
+		if path.Ext(file) != ".html" {
+			continue
+		}
+		loc := "/" + strings.TrimSuffix(file, "index.html")
+		urlSet.URLs = append(urlSet.URLs, sitemapURL{Loc: strings.TrimSuffix(baseURL, "/") + loc})
+	}
+	sort.Slice(urlSet.URLs, func(i, j int) bool { return urlSet.URLs[i].Loc < urlSet.URLs[j].Loc })
+
+	data, err := xml.MarshalIndent(urlSet, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(outDir, "sitemap.xml"), data, 0o644)
+}
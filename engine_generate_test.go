@@ -0,0 +1,106 @@
+package wax_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Generate(t *testing.T) {
+	fs := fstest.MapFS{
+		"Home.jsx": &fstest.MapFile{Data: []byte(`export function Home(p) { return <h1>{p.title}</h1> }`)},
+		"Post.jsx": &fstest.MapFile{Data: []byte(`export function Post(p) { return <article>{p.slug}</article> }`)},
+		"Feed.jsx": &fstest.MapFile{Data: []byte(`export function Feed() { return <rss/> }`)},
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "home.json"), []byte(`{"title": "Welcome"}`), 0o644)
+	os.MkdirAll(filepath.Join(dir, "static", "css"), 0o755)
+	os.WriteFile(filepath.Join(dir, "static", "css", "site.css"), []byte(`body{}`), 0o644)
+	out := filepath.Join(dir, "public")
+
+	err := wax.New(wax.NewFsViewResolver(fs)).Generate(context.Background(), wax.GenerateOptions{
+		OutDir: out,
+		Routes: []wax.Route{
+			{Path: "/", View: "Home", ModelFile: filepath.Join(dir, "home.json")},
+			{Path: "/feed.xml", View: "Feed"},
+		},
+		Generators: []wax.RouteGenerator{func(ctx context.Context) ([]wax.Route, error) {
+			var routes []wax.Route
+			for _, slug := range []string{"first", "second"} {
+				routes = append(routes, wax.Route{Path: "/blog/" + slug, View: "Post", Model: map[string]any{"slug": slug}})
+			}
+			return routes, nil
+		}},
+		StaticDir: filepath.Join(dir, "static"),
+		BaseURL:   "https://example.com/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"index.html":             "<h1>Welcome</h1>",
+		"blog/first/index.html":  "<article>first</article>",
+		"blog/second/index.html": "<article>second</article>",
+		"feed.xml":               "<rss></rss>",
+		"css/site.css":           "body{}",
+	}
+	for file, content := range expected {
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(file)))
+		if err != nil {
+			t.Errorf("missing file %s: %v", file, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("invalid content of %s > \n\tgot      : %s\n\texpected : %s", file, data, content)
+		}
+	}
+
+	sitemap, _ := os.ReadFile(filepath.Join(out, "sitemap.xml"))
+	for _, loc := range []string{"https://example.com/", "https://example.com/blog/first/", "https://example.com/blog/second/"} {
+		if !strings.Contains(string(sitemap), "<loc>"+loc+"</loc>") {
+			t.Errorf("sitemap does not contain %s:\n%s", loc, sitemap)
+		}
+	}
+	if strings.Contains(string(sitemap), "feed.xml") {
+		t.Errorf("sitemap contains not HTML page:\n%s", sitemap)
+	}
+
+	t.Run("failed_route", func(t *testing.T) {
+		fs["Broken.jsx"] = &fstest.MapFile{Data: []byte(`export function Broken(p) { return <h1>{p.missing.title}</h1> }`)}
+		err := wax.New(wax.NewFsViewResolver(fs)).Generate(context.Background(), wax.GenerateOptions{
+			OutDir: out,
+			Routes: []wax.Route{{Path: "/", View: "Broken"}},
+		})
+		if err == nil {
+			t.Fatal("expected error of broken route")
+		}
+		if data, _ := os.ReadFile(filepath.Join(out, "index.html")); string(data) != "<h1>Welcome</h1>" {
+			t.Errorf("page of failed route changed: %s", data)
+		}
+		if temp, _ := filepath.Glob(filepath.Join(out, ".wax-*")); len(temp) != 0 {
+			t.Errorf("temporary files left: %v", temp)
+		}
+	})
+	t.Run("duplicate_output", func(t *testing.T) {
+		dupOut := filepath.Join(dir, "duplicate")
+		err := wax.New(wax.NewFsViewResolver(fs)).Generate(context.Background(), wax.GenerateOptions{
+			OutDir: dupOut,
+			Routes: []wax.Route{
+				{Path: "/blog", View: "Home"},
+				{Path: "/blog/index.html", View: "Post", Model: map[string]any{"slug": "x"}},
+			},
+		})
+		if err == nil || !strings.Contains(err.Error(), "blog/index.html") {
+			t.Fatalf("expected error of routes with the same file, got %v", err)
+		}
+		if _, err := os.Stat(dupOut); !os.IsNotExist(err) {
+			t.Errorf("output written despite duplicate routes")
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package wax
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithLayoutFiles wraps views with layout files named name (e.g. "_layout") found in view directory and its parents.
+// Closest layout is the innermost one. View exporting `layout` overrides this lookup.
+func WithLayoutFiles(name string) Option {
+	return func(e *Engine) {
+		e.layoutFile = name
+	}
+}
+
+type layout struct {
+	module ModuleMeta
+	render goja.Callable
+}
+
+// resolveLayouts returns layouts for the view, from innermost to outermost.
+//
+// View module can export `layout` with path to layout module (`false` disables layouts).
+// Layout module exports component as default (or `Layout`) and can point to its own layout the same way.
+func (e *Engine) resolveLayouts(context *runContext, waxObj *waxJSObj, view ModuleMeta, viewModule goja.Value) ([]layout, error) {
+	switch declared := layoutExport(viewModule); {
+	case declared == nil:
+		return e.findLayoutFiles(context, waxObj, view)
+	case declared.ExportType() == reflectTypeString:
+		return e.followLayouts(context, waxObj, view, viewModule, map[string]bool{view.URL.String(): true})
+	default:
+		return nil, nil
+	}
+}
+
+// followLayouts loads layouts declared by module and its layouts. Seen are modules already used on the page.
+func (e *Engine) followLayouts(context *runContext, waxObj *waxJSObj, module ModuleMeta, moduleValue goja.Value, seen map[string]bool) ([]layout, error) {
+	var result []layout
+	for {
+		declared := layoutExport(moduleValue)
+		if declared == nil || declared.ExportType() != reflectTypeString {
+			return result, nil
+		}
+		layoutURI, err := context.ViewResolver.ResolveModuleFile(module, declared.String())
+		if err != nil {
+			return nil, Error{
+				File:  *module.URL,
+				Phase: PhaseLoading,
+				Err:   fmt.Errorf("could not resolve layout '%s': %w", declared.String(), err),
+			}
+		}
+		if seen[layoutURI.String()] {
+			return nil, Error{
+				File:  *layoutURI,
+				Phase: PhaseLoading,
+				Err:   fmt.Errorf("layout '%s' is used twice", layoutURI.Path),
+			}
+		}
+		seen[layoutURI.String()] = true
+
+		l, value, err := e.loadLayout(context, waxObj, layoutURI)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, l)
+		module, moduleValue = l.module, value
+	}
+}
+
+// findLayoutFiles looks for layout files from view directory up to the root.
+// Layout file exporting `layout` stops the lookup - `false` ends layouts there, path is followed like in views.
+func (e *Engine) findLayoutFiles(context *runContext, waxObj *waxJSObj, view ModuleMeta) ([]layout, error) {
+	if e.layoutFile == "" {
+		return nil, nil
+	}
+	var result []layout
+	seen := map[string]bool{view.URL.String(): true}
+	for level := 0; level < layoutFileLevels(view); level++ {
+		layoutURI, err := e.resolveLayoutFile(context.ViewResolver, view, level)
+		if err != nil {
+			return nil, err
+		}
+		if layoutURI == nil || seen[layoutURI.String()] {
+			continue
+		}
+		seen[layoutURI.String()] = true
+		l, value, err := e.loadLayout(context, waxObj, layoutURI)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, l)
+		if declared := layoutExport(value); declared != nil {
+			if declared.ExportType() == reflectTypeString {
+				declaredLayouts, err := e.followLayouts(context, waxObj, l.module, value, seen)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, declaredLayouts...)
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+// layoutFileLevels returns number of directories searched for layout files of the view, its directory and parents.
+func layoutFileLevels(view ModuleMeta) int {
+	dir := path.Dir(view.URL.Path)
+	if dir == "/" {
+		return 1
+	}
+	return strings.Count(strings.Trim(dir, "/"), "/") + 2
+}
+
+// resolveLayoutFile resolves layout file in directory level above the view directory, nil when there is none.
+func (e *Engine) resolveLayoutFile(viewResolver ViewResolver, view ModuleMeta, level int) (*url.URL, error) {
+	layoutURI, err := viewResolver.ResolveModuleFile(view, "./"+strings.Repeat("../", level)+e.layoutFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, Error{
+			File:  *view.URL,
+			Phase: PhaseLoading,
+			Err:   fmt.Errorf("could not resolve layout file '%s': %w", e.layoutFile, err),
+		}
+	}
+	return layoutURI, nil
+}
+
+func (e *Engine) loadLayout(context *runContext, waxObj *waxJSObj, layoutURI *url.URL) (layout, goja.Value, error) {
+	module, err := e.load(context, waxObj, layoutURI)
+	if err != nil {
+		return layout{}, nil, err
+	}
+	component := module.ToObject(waxObj.vm).Get("default")
+	if component == nil {
+		component = moduleExports(module).Get("Layout")
+	}
+	render, ok := goja.AssertFunction(component)
+	if !ok {
+		return layout{}, nil, Error{
+			File:  *layoutURI,
+			Phase: PhaseLoading,
+			Err:   fmt.Errorf("expected layout '%s' to export default function", layoutURI.Path),
+		}
+	}
+	return layout{module: ModuleMeta{URL: layoutURI}, render: render}, module, nil
+}
+
+func layoutExport(module goja.Value) goja.Value {
+	v := moduleExports(module).Get("layout")
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return nil
+	}
+	return v
+}
+
+func moduleExports(module goja.Value) *goja.Object {
+	exports, ok := module.(*goja.Object).Get("exports").(*goja.Object)
+	if !ok {
+		panic("invalid state: no exports")
+	}
+	return exports
+}
+
+// wrapWithLayouts passes content to layouts as children, from innermost to outermost.
+func wrapWithLayouts(vm *goja.Runtime, content goja.Value, layouts []layout, model any) goja.Value {
+	for _, l := range layouts {
+		props := vm.NewObject()
+		props.Set("children", content)
+		props.Set("model", model)
+		v, err := l.render(goja.Undefined(), props)
+		if err != nil {
+			panic(err)
+		}
+		content = v
+	}
+	return content
+}
@@ -0,0 +1,114 @@
+package wax_test
+
+import (
+	"bytes"
+	"errors"
+	iofs "io/fs"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Layouts(t *testing.T) {
+	fs := fstest.MapFS{
+		"_layout.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Root(p) {
+                return <html><body>{p.children}</body></html>
+            }`)},
+		"admin/_layout.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Admin(p) {
+                return <main title={p.model.title}>{p.children}</main>
+            }`)},
+		"admin/Users.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Users(p) { return <ul><li>{p.title}</li></ul> }`)},
+		"Home.jsx": &fstest.MapFile{Data: []byte(`
+            export function Home(p) { return <h1>{p.title}</h1> }`)},
+		"Fragment.jsx": &fstest.MapFile{Data: []byte(`
+            export const layout = false
+            export function Fragment(p) { return <i>{p.title}</i> }`)},
+		"Explicit.jsx": &fstest.MapFile{Data: []byte(`
+            export const layout = "./shell/Inner.jsx"
+            export function Explicit(p) { return <b>{p.title}</b> }`)},
+		"shell/Inner.jsx": &fstest.MapFile{Data: []byte(`
+            export const layout = "./Outer.jsx"
+            export function Layout(p) { return <section>{p.children}</section> }`)},
+		"shell/Outer.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Outer(p) { return <article>{p.children}</article> }`)},
+		"docs/_layout.jsx": &fstest.MapFile{Data: []byte(`
+            export const layout = "../shell/Outer.jsx"
+            export default function Docs(p) { return <nav>{p.children}</nav> }`)},
+		"docs/Page.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Page(p) { return <p>{p.title}</p> }`)},
+	}
+	model := map[string]any{"title": "T"}
+
+	checks := []struct {
+		view     string
+		options  []wax.Option
+		expected string
+	}{
+		{view: "Home", expected: `<h1>T</h1>`},
+		{view: "Home", options: []wax.Option{wax.WithLayoutFiles("_layout")}, expected: `<html><body><h1>T</h1></body></html>`},
+		{view: "admin/Users", options: []wax.Option{wax.WithLayoutFiles("_layout")}, expected: `<html><body><main title="T"><ul><li>T</li></ul></main></body></html>`},
+		{view: "Fragment", options: []wax.Option{wax.WithLayoutFiles("_layout")}, expected: `<i>T</i>`},
+		{view: "Explicit", expected: `<article><section><b>T</b></section></article>`},
+		{view: "Explicit", options: []wax.Option{wax.WithLayoutFiles("_layout")}, expected: `<article><section><b>T</b></section></article>`},
+		{view: "docs/Page", options: []wax.Option{wax.WithLayoutFiles("_layout")}, expected: `<article><nav><p>T</p></nav></article>`},
+	}
+	for _, check := range checks {
+		t.Run(check.view, func(t *testing.T) {
+			engine := wax.New(wax.NewFsViewResolver(fs), check.options...)
+			buf := bytes.NewBufferString("")
+			if err := engine.Render(buf, check.view, model); err != nil {
+				t.Fatal(err)
+			}
+			compareHTML(t, check.view, check.expected, buf.String())
+		})
+	}
+}
+
+type failingLayoutResolver struct {
+	wax.ViewResolver
+}
+
+func (r failingLayoutResolver) ResolveModuleFile(from wax.ModuleMeta, importPath string) (*url.URL, error) {
+	if strings.HasSuffix(importPath, "_layout") {
+		return nil, errors.New("resolver is down")
+	}
+	return r.ViewResolver.ResolveModuleFile(from, importPath)
+}
+
+func Test_Engine_LayoutFilesResolverError(t *testing.T) {
+	fs := fstest.MapFS{
+		"Home.jsx": &fstest.MapFile{Data: []byte(`export function Home(p) { return <h1>home</h1> }`)},
+	}
+	engine := wax.New(failingLayoutResolver{wax.NewFsViewResolver(fs)}, wax.WithLayoutFiles("_layout"))
+	err := engine.Render(bytes.NewBufferString(""), "Home", nil)
+	if err == nil || !strings.Contains(err.Error(), "resolver is down") {
+		t.Errorf("expected resolver error, got %v", err)
+	}
+}
+
+func Test_Engine_LayoutFilesCustomResolveFunc(t *testing.T) {
+	fs := fstest.MapFS{
+		"pages/Home.page.jsx": &fstest.MapFile{Data: []byte(`export default function Home(p) { return <h1>home</h1> }`)},
+		"_layout.page.jsx":    &fstest.MapFile{Data: []byte(`export default function Layout(p) { return <main>{p.children}</main> }`)},
+	}
+	// resolve func of own naming scheme, missing files are reported with fs.ErrNotExist
+	resolve := func(onFS iofs.FS, name string) (*url.URL, error) {
+		file := name + ".page.jsx"
+		if _, err := iofs.Stat(onFS, file); err != nil {
+			return nil, err
+		}
+		return url.Parse("file:///" + file)
+	}
+	engine := wax.New(wax.NewFsViewResolverCustom(fs, resolve), wax.WithLayoutFiles("_layout"))
+	buf := bytes.NewBufferString("")
+	if err := engine.Render(buf, "pages/Home", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "custom", "<main><h1>home</h1></main>", buf.String())
+}
@@ -0,0 +1,75 @@
+package wax
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	ErrExecutionTimeExceeded = errors.New("execution time limit exceeded")
+	ErrCallStackExceeded     = errors.New("call stack size limit exceeded")
+	ErrOutputSizeExceeded    = errors.New("output size limit exceeded")
+)
+
+// WithMaxExecutionTime limits wall-clock time of single render.
+func WithMaxExecutionTime(d time.Duration) Option {
+	return func(e *Engine) {
+		e.limits.executionTime = d
+	}
+}
+
+// WithMaxCallStackSize limits JS call depth (see goja.Runtime.SetMaxCallStackSize).
+func WithMaxCallStackSize(size int) Option {
+	return func(e *Engine) {
+		e.limits.callStackSize = size
+	}
+}
+
+// WithMaxOutputSize limits number of bytes written by single render.
+func WithMaxOutputSize(bytes int64) Option {
+	return func(e *Engine) {
+		e.limits.outputSize = bytes
+	}
+}
+
+type renderLimits struct {
+	executionTime time.Duration
+	callStackSize int
+	outputSize    int64
+}
+
+func (l renderLimits) apply(rc *runContext) context.CancelFunc {
+	cancel := context.CancelFunc(func() {})
+	if l.executionTime > 0 {
+		rc.ctx, cancel = context.WithTimeoutCause(rc.ctx, l.executionTime, ErrExecutionTimeExceeded)
+	}
+	if l.outputSize > 0 {
+		rc.out = &limitedWriter{out: rc.out, remaining: l.outputSize}
+	}
+	return cancel
+}
+
+type limitedWriter struct {
+	out       io.Writer
+	remaining int64
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > w.remaining {
+		return 0, ErrOutputSizeExceeded
+	}
+	w.remaining -= int64(len(p))
+	return w.out.Write(p)
+}
+
+func (w *limitedWriter) Flush() error {
+	return flush(w.out)
+}
+
+func isLimitError(err error) bool {
+	return errors.Is(err, ErrExecutionTimeExceeded) ||
+		errors.Is(err, ErrCallStackExceeded) ||
+		errors.Is(err, ErrOutputSizeExceeded)
+}
@@ -0,0 +1,75 @@
+package wax_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Limits(t *testing.T) {
+	fs := fstest.MapFS{
+		"Loop.jsx": &fstest.MapFile{Data: []byte(`
+            export function Loop() {
+                while (true) {}
+            }`)},
+		"Recursion.jsx": &fstest.MapFile{Data: []byte(`
+            export function Recursion(p) {
+                return <div><Recursion depth={p.depth + 1}/></div>
+            }`)},
+		"Huge.jsx": &fstest.MapFile{Data: []byte(`
+            export function Huge() {
+                const items = []
+                for (let i = 0; i < 10000; i++) items.push(<li>item {i}</li>)
+                return <ul>{items}</ul>
+            }`)},
+		"Small.jsx": &fstest.MapFile{Data: []byte(`export function Small() { return <i>ok</i> }`)},
+	}
+
+	checks := []struct {
+		view     string
+		limit    wax.Option
+		expected error
+	}{
+		{view: "Loop", limit: wax.WithMaxExecutionTime(50 * time.Millisecond), expected: wax.ErrExecutionTimeExceeded},
+		{view: "Recursion", limit: wax.WithMaxCallStackSize(200), expected: wax.ErrCallStackExceeded},
+		{view: "Huge", limit: wax.WithMaxOutputSize(1024), expected: wax.ErrOutputSizeExceeded},
+	}
+	for _, check := range checks {
+		t.Run(check.view, func(t *testing.T) {
+			engine := wax.New(wax.NewFsViewResolver(fs), check.limit)
+			buf := bytes.NewBufferString("")
+			err := engine.Render(buf, check.view, map[string]any{"depth": 0})
+
+			var waxError wax.Error
+			if !errors.As(err, &waxError) {
+				t.Fatalf("expected wax.Error, got %v", err)
+			}
+			if waxError.Phase != wax.PhaseLimit {
+				t.Errorf("invalid phase > \n\tgot      : %s\n\texpected : %s", waxError.Phase, wax.PhaseLimit)
+			}
+			if !errors.Is(err, check.expected) {
+				t.Errorf("invalid error > \n\tgot      : %v\n\texpected : %v", err, check.expected)
+			}
+			if buf.Len() > 1024 {
+				t.Errorf("written %d bytes over the limit", buf.Len())
+			}
+		})
+	}
+
+	t.Run("within_limits", func(t *testing.T) {
+		engine := wax.New(wax.NewFsViewResolver(fs),
+			wax.WithMaxExecutionTime(time.Second),
+			wax.WithMaxCallStackSize(200),
+			wax.WithMaxOutputSize(1024),
+		)
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "Small", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "within_limits", "<i>ok</i>", buf.String())
+	})
+}
@@ -0,0 +1,54 @@
+package wax
+
+import (
+	"github.com/dop251/goja"
+)
+
+// WithService registers Go service passed to view loaders. RunBinding.Services override services with the same name.
+// Functions taking context.Context as first argument get render context (see WithGlobalObject).
+func WithService(name string, s any) Option {
+	return func(e *Engine) {
+		if e.services == nil {
+			e.services = make(map[string]any)
+		}
+		e.services[name] = s
+	}
+}
+
+// loaderExport returns `loader` function exported by view module, if any.
+func loaderExport(viewModule goja.Value) (goja.Callable, bool) {
+	loader := moduleExports(viewModule).Get("loader")
+	if loader == nil {
+		return nil, false
+	}
+	return goja.AssertFunction(loader)
+}
+
+// runLoader calls loader with render model as params and returns its (awaited) result, the model for the view.
+// Must be called outside of JS code.
+func (e *Engine) runLoader(rt *jsRuntime, loader goja.Callable, context *runContext) goja.Value {
+	vm := rt.vm
+	services := make(map[string]any, len(e.services)+len(context.Services))
+	for k, v := range e.services {
+		services[k] = rt.wax.bindContext(v)
+	}
+	for k, v := range context.Services {
+		services[k] = rt.wax.bindContext(v)
+	}
+
+	model, err := loader(goja.Undefined(), vm.ToValue(context.Model), vm.ToValue(services))
+	if err != nil {
+		panic(err)
+	}
+	promise, ok := model.Export().(*goja.Promise)
+	if !ok {
+		return model
+	}
+	if err := context.loop.await(promise); err != nil {
+		panic(vm.NewGoError(err))
+	}
+	if promise.State() == goja.PromiseStateRejected {
+		panic(promise.Result())
+	}
+	return promise.Result()
+}
@@ -0,0 +1,87 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Loader(t *testing.T) {
+	fs := fstest.MapFS{
+		"User.jsx": &fstest.MapFile{Data: []byte(`
+            export function loader(params, services) {
+                return { user: services.users.Get(params.id), by: services.requestBy }
+            }
+            export function User(p) { return <h1>{p.user} for {p.by}</h1> }`)},
+		"AsyncUser.jsx": &fstest.MapFile{Data: []byte(`
+            export async function loader(params, services) {
+                const user = await services.users.Load(params.id)
+                return { user }
+            }
+            export function AsyncUser(p) { return <h1>{p.user}</h1> }
+            export function Badge(p) { return <b>{p.user}</b> }`)},
+		"Failing.jsx": &fstest.MapFile{Data: []byte(`
+            export async function loader(params, services) {
+                await services.users.Load("")
+            }
+            export function Failing(p) { return <h1>never</h1> }`)},
+	}
+	errNotFound := errors.New("user not found")
+	users := map[string]any{
+		"Get": func(id string) string { return "user-" + id },
+		"Load": func(ctx context.Context, id string) any {
+			return wax.Async(ctx, func() (any, error) {
+				if id == "" {
+					return nil, errNotFound
+				}
+				return "user-" + id, nil
+			})
+		},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), wax.WithService("users", users), wax.WithRuntimePool(1))
+
+	render := func(view string, binding wax.RunBinding) (string, error) {
+		binding.ViewResolver = wax.NewFsViewResolver(fs)
+		buf := bytes.NewBufferString("")
+		err := engine.RenderWith(buf, view, binding)
+		return buf.String(), err
+	}
+
+	t.Run("sync", func(t *testing.T) {
+		out, err := render("User", wax.RunBinding{
+			Model:    map[string]any{"id": "1"},
+			Services: map[string]any{"requestBy": "admin"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "sync", "<h1>user-1 for admin</h1>", out)
+	})
+
+	t.Run("async", func(t *testing.T) {
+		out, err := render("AsyncUser", wax.RunBinding{Model: map[string]any{"id": "2"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "async", "<h1>user-2</h1>", out)
+	})
+
+	t.Run("partial_skips_loader", func(t *testing.T) {
+		out, err := render("AsyncUser#Badge", wax.RunBinding{Model: map[string]any{"user": "props"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "partial", "<b>props</b>", out)
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		_, err := render("Failing", wax.RunBinding{})
+		if !errors.Is(err, errNotFound) {
+			t.Errorf("expected loader error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package wax
+
+import (
+	"net/url"
+
+	"github.com/dop251/goja"
+)
+
+// registeredModuleScheme is URL scheme of modules registered with WithNativeModule and WithVirtualModule.
+const registeredModuleScheme = "wax"
+
+type registeredModule struct {
+	exports map[string]any
+	source  string
+}
+
+// WithNativeModule registers module implemented in Go, imported in views by name (e.g. `import { format } from "go:money"`).
+// Map entries are module exports, entry "default" is the default export.
+// Functions taking context.Context as first argument get render context (see WithGlobalObject).
+func WithNativeModule(name string, exports map[string]any) Option {
+	return func(e *Engine) {
+		e.registerModule(name, registeredModule{exports: exports})
+	}
+}
+
+// WithVirtualModule registers JS/JSX/TS source as module imported in views by name.
+// Relative imports of virtual module are resolved from views root.
+func WithVirtualModule(name string, source string) Option {
+	return func(e *Engine) {
+		e.registerModule(name, registeredModule{source: source})
+	}
+}
+
+func (e *Engine) registerModule(name string, m registeredModule) {
+	if e.modules == nil {
+		e.modules = make(map[string]registeredModule)
+	}
+	e.modules[name] = m
+}
+
+// resolveModule resolves import of module registered in the engine or, for other paths, file from the view resolver.
+// Import query (e.g. "?raw") is kept in URL fragment.
+func (e *Engine) resolveModule(viewResolver ViewResolver, from ModuleMeta, importPath string) (*url.URL, error) {
+	if _, ok := e.modules[importPath]; ok {
+		return &url.URL{Scheme: registeredModuleScheme, Opaque: importPath}, nil
+	}
+	if from.URL.Scheme == registeredModuleScheme {
+		from = ModuleMeta{URL: rootModuleURL}
+	}
+	importPath, query := splitImportQuery(importPath)
+	moduleURL, err := viewResolver.ResolveModuleFile(from, importPath)
+	if err != nil || query == "" {
+		return moduleURL, err
+	}
+	withQuery := *moduleURL
+	withQuery.Fragment = query
+	return &withQuery, nil
+}
+
+func (e *Engine) registeredModule(moduleURL *url.URL) (registeredModule, bool) {
+	if moduleURL.Scheme != registeredModuleScheme {
+		return registeredModule{}, false
+	}
+	m, ok := e.modules[moduleURL.Opaque]
+	return m, ok
+}
+
+func isNativeModule(m registeredModule) bool {
+	return m.exports != nil
+}
+
+// defineNativeModule defines module object with Go exports.
+func (c *waxJSObj) defineNativeModule(moduleURL *url.URL, exports map[string]any) goja.Value {
+	module := c.DefineModule(&ModuleMeta{URL: moduleURL}).(*goja.Object)
+	moduleExports := module.Get("exports").(*goja.Object)
+	for k, v := range exports {
+		if k == "default" {
+			module.Set("default", c.bindContext(v))
+			continue
+		}
+		moduleExports.Set(k, c.bindContext(v))
+	}
+	return module
+}
@@ -0,0 +1,52 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_RegisteredModules(t *testing.T) {
+	fs := fstest.MapFS{
+		"Price.tsx": &fstest.MapFile{Data: []byte(`
+            import money, { format, currency } from "go:money"
+            import { Badge } from "ui:badge"
+            export function Price(p: {value: number}) {
+                return <Badge>{format(p.value)} {currency()} {money.name}</Badge>
+            }`)},
+		"parts/Strong.tsx": &fstest.MapFile{Data: []byte(`
+            export function Strong(p) { return <strong>{p.children}</strong> }`)},
+	}
+	type ctxKey struct{}
+	engine := wax.New(wax.NewFsViewResolver(fs),
+		wax.WithNativeModule("go:money", map[string]any{
+			"format":   func(v float64) string { return fmt.Sprintf("%.2f", v) },
+			"currency": func(ctx context.Context) string { return ctx.Value(ctxKey{}).(string) },
+			"default":  map[string]any{"name": "money"},
+		}),
+		wax.WithVirtualModule("ui:badge", `
+            import { Strong } from "./parts/Strong.tsx"
+            export function Badge(p) { return <span class="badge"><Strong>{p.children}</Strong></span> }`),
+	)
+
+	for _, attempt := range []string{"first", "cached"} {
+		t.Run(attempt, func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			ctx := context.WithValue(context.Background(), ctxKey{}, "PLN")
+			if err := engine.RenderContext(ctx, buf, "Price", map[string]any{"value": 2.5}); err != nil {
+				t.Fatal(err)
+			}
+			compareHTML(t, attempt, `<span class="badge"><strong>2.50 PLN money</strong></span>`, buf.String())
+		})
+	}
+
+	t.Run("precompile", func(t *testing.T) {
+		if err := engine.Precompile(context.Background(), "Price"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
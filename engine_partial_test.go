@@ -0,0 +1,58 @@
+package wax_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_RenderPartial(t *testing.T) {
+	fs := fstest.MapFS{
+		"_layout.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Root(p) { return <html>{p.children}</html> }`)},
+		"todos/Page.jsx": &fstest.MapFile{Data: []byte(`
+            export function Row(p) {
+                return <li id={"todo-" + p.id}>{p.title}</li>
+            }
+            export default function Page(p) {
+                return <ul>{p.todos.map(t => <Row {...t}/>)}</ul>
+            }`)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), wax.WithLayoutFiles("_layout"))
+	props := map[string]any{"id": 1, "title": "milk"}
+
+	t.Run("RenderPartial", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		if err := engine.RenderPartial(buf, "todos/Page", "Row", props); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "RenderPartial", `<li id="todo-1">milk</li>`, buf.String())
+	})
+
+	t.Run("view_name_with_export", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "todos/Page#Row", props); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "view_name_with_export", `<li id="todo-1">milk</li>`, buf.String())
+	})
+
+	t.Run("default_export", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		if err := engine.RenderPartial(buf, "todos/Page", "default", map[string]any{"todos": []any{props}}); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "default_export", `<ul><li id="todo-1">milk</li></ul>`, buf.String())
+	})
+
+	t.Run("missing_export", func(t *testing.T) {
+		err := engine.RenderPartial(bytes.NewBufferString(""), "todos/Page", "Missing", props)
+		var waxError wax.Error
+		if !errors.As(err, &waxError) || waxError.Phase != wax.PhaseLoading {
+			t.Fatalf("expected wax.Error with loading phase, got %v", err)
+		}
+	})
+}
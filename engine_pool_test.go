@@ -0,0 +1,167 @@
+package wax_test
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_RuntimePool(t *testing.T) {
+	var globalScriptRuns atomic.Int32
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`
+            export function View(model) {
+                const leaked = typeof perRequest === "undefined" ? "none" : perRequest
+                const previous = typeof fromView
+                globalThis.fromView = model.name
+                return <div>{helpers.greet(model.name)} - {leaked} - {previous}</div>
+            }`)},
+		"globals.js": &fstest.MapFile{Data: []byte(`
+            counter.Inc()
+            globalThis.helpers = { greet: (n) => "Hello " + n }
+            `)},
+	}
+
+	viewResolver := wax.NewFsViewResolver(fs)
+	engine := wax.New(viewResolver,
+		wax.WithRuntimePool(2),
+		wax.WithGlobalScript("./globals.js"),
+		wax.WithGlobalObject("counter", map[string]any{"Inc": func() { globalScriptRuns.Add(1) }}),
+	)
+
+	buf := bytes.NewBufferString("")
+	err := engine.RenderWith(buf, "View", wax.RunBinding{
+		ViewResolver: viewResolver,
+		Model:        map[string]any{"name": "first"},
+		Globals:      map[string]any{"perRequest": "binding-value"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "pool_first", "<div>Hello first - binding-value - undefined</div>", buf.String())
+
+	for _, name := range []string{"second", "third"} {
+		buf.Reset()
+		if err := engine.Render(buf, "View", map[string]any{"name": name}); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "pool_"+name, "<div>Hello "+name+" - none - undefined</div>", buf.String())
+	}
+
+	if runs := globalScriptRuns.Load(); runs != 1 {
+		t.Errorf("expected global script to run once, got %d", runs)
+	}
+}
+
+func Test_Engine_RuntimePool_Leaks(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`
+            export function View(model) {
+                const seen = [typeof secret, typeof [].leak, typeof globalThis[Symbol.for("hidden")]].join(",")
+                Object.defineProperty(globalThis, "secret", { value: model.name })
+                Array.prototype.leak = model.name
+                globalThis[Symbol.for("hidden")] = model.name
+                return <i>{seen}</i>
+            }`)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), wax.WithRuntimePool(1))
+
+	for _, name := range []string{"first", "second"} {
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "View", map[string]any{"name": name}); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, name, "<i>undefined,undefined,undefined</i>", buf.String())
+	}
+}
+
+func Test_Engine_RuntimePool_GlobalScriptsState(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`
+            import { count } from "./counter.js"
+            export function View(model) {
+                const seen = [config.items.length, cache.size, config.nested.list.length, count()].join(",")
+                if (model.mutate) {
+                    config.items.push(model.name)
+                    cache.set(model.name, true)
+                    config.nested.list[0] = model.name
+                }
+                return <i>{seen}</i>
+            }`)},
+		"counter.js": &fstest.MapFile{Data: []byte(`
+            let n = 0
+            export function count() { return n++ }`)},
+		"globals.js": &fstest.MapFile{Data: []byte(`
+            import { count } from "./counter.js"
+            globalThis.config = { items: [], nested: { list: [] } }
+            globalThis.cache = new Map()
+            `)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), wax.WithRuntimePool(1), wax.WithGlobalScript("./globals.js"))
+
+	// changed objects are detected and runtime is dropped, variables in closures are not (documented limit)
+	for i, check := range []struct {
+		mutate   bool
+		expected string
+	}{{true, "0,0,0,0"}, {true, "0,0,0,0"}, {false, "0,0,0,0"}, {false, "0,0,0,1"}} {
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "View", map[string]any{"name": strconv.Itoa(i), "mutate": check.mutate}); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, strconv.Itoa(i), "<i>"+check.expected+"</i>", buf.String())
+	}
+}
+
+func Test_Engine_RuntimePool_GlobalScriptsViewResolver(t *testing.T) {
+	// modification time is a part of module URL, so both files are not the same module for program cache
+	resolver := func(greeting string, modTime time.Time) wax.ViewResolver {
+		return wax.NewFsViewResolver(fstest.MapFS{
+			"View.jsx":   &fstest.MapFile{Data: []byte(`export function View(model) { return <i>{greet()}</i> }`)},
+			"globals.js": &fstest.MapFile{Data: []byte(`globalThis.greet = () => "` + greeting + `"`), ModTime: modTime},
+		})
+	}
+	hello, hi := resolver("hello", time.Unix(1, 0)), resolver("hi", time.Unix(2, 0))
+	engine := wax.New(hello, wax.WithRuntimePool(1), wax.WithGlobalScript("./globals.js"))
+
+	for _, r := range []struct {
+		name     string
+		resolver wax.ViewResolver
+	}{{"hello", hello}, {"hi", hi}, {"hello", hello}} {
+		buf := bytes.NewBufferString("")
+		if err := engine.RenderWith(buf, "View", wax.RunBinding{ViewResolver: r.resolver}); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, r.name, "<i>"+r.name+"</i>", buf.String())
+	}
+}
+
+func Test_Engine_RuntimePool_Concurrent(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`export function View(model) { return <i>{model.id}</i> }`)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs), wax.WithRuntimePool(4))
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			buf := bytes.NewBufferString("")
+			if err := engine.Render(buf, "View", map[string]any{"id": id}); err != nil {
+				t.Error(err)
+				return
+			}
+			expected := "<i>" + strconv.Itoa(id) + "</i>"
+			if buf.String() != expected {
+				t.Errorf("got %s, expected %s", buf.String(), expected)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,155 @@
+package wax
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"runtime"
+	"sync"
+)
+
+// ModuleLister is implemented by view resolvers able to list all modules they can resolve.
+type ModuleLister interface {
+	ListModules() ([]*url.URL, error)
+}
+
+// Precompile transpiles and compiles views and modules they import, so first render does not have to.
+// Layout files of views (see WithLayoutFiles) and global scripts are compiled too. Layouts declared
+// with `layout` export are known only when view runs, they are compiled if imported or listed by PrecompileAll.
+// Returned error joins errors of all modules that failed.
+func (e *Engine) Precompile(ctx context.Context, viewNames ...string) error {
+	var errs []error
+	modules := make([]*url.URL, 0, len(viewNames))
+	for _, viewName := range viewNames {
+		viewURI, err := e.viewResolver.ResolveViewFile(viewName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		modules = append(modules, viewURI)
+	}
+	errs = append(errs, e.precompile(ctx, modules))
+	return errors.Join(errs...)
+}
+
+// PrecompileAll compiles every module listed by view resolver, their layout files and global scripts.
+// Resolver must implement ModuleLister.
+func (e *Engine) PrecompileAll(ctx context.Context) error {
+	lister, ok := e.viewResolver.(ModuleLister)
+	if !ok {
+		return errors.New("view resolver can not list modules")
+	}
+	modules, err := lister.ListModules()
+	if err != nil {
+		return err
+	}
+	return e.precompile(ctx, modules)
+}
+
+// precompile compiles modules, layout files of modules and global scripts, following their imports.
+func (e *Engine) precompile(ctx context.Context, modules []*url.URL) error {
+	modules, errs := e.entryModules(modules)
+	var (
+		mu      sync.Mutex
+		visited = make(map[string]bool)
+		wg      sync.WaitGroup
+		workers = make(chan struct{}, runtime.GOMAXPROCS(0))
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	var enqueue func(u *url.URL)
+	enqueue = func(u *url.URL) {
+		mu.Lock()
+		defer mu.Unlock()
+		if visited[u.String()] {
+			return
+		}
+		visited[u.String()] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			workers <- struct{}{}
+			defer func() { <-workers }()
+			e.precompileModule(ctx, u, enqueue, fail)
+		}()
+	}
+
+	for _, u := range modules {
+		enqueue(u)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// entryModules returns modules with their layout files and global scripts, which are loaded without import.
+func (e *Engine) entryModules(modules []*url.URL) ([]*url.URL, []error) {
+	var errs []error
+	result := append([]*url.URL(nil), modules...)
+	if e.layoutFile != "" {
+		for _, u := range modules {
+			view := ModuleMeta{URL: u}
+			for level := 0; level < layoutFileLevels(view); level++ {
+				layoutURI, err := e.resolveLayoutFile(e.viewResolver, view, level)
+				if err != nil {
+					errs = append(errs, err)
+					break
+				}
+				if layoutURI != nil {
+					result = append(result, layoutURI)
+				}
+			}
+		}
+	}
+	for _, script := range e.globalScripts {
+		scriptURI, err := e.viewResolver.ResolveModuleFile(ModuleMeta{URL: rootModuleURL}, script)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result = append(result, scriptURI)
+	}
+	return result, errs
+}
+
+func (e *Engine) precompileModule(ctx context.Context, moduleURL *url.URL, enqueue func(*url.URL), fail func(error)) {
+	if ctx.Err() != nil {
+		return
+	}
+	compiled, err := e.compileModule(moduleURL, e.viewResolver)
+	if err != nil {
+		if !errors.As(err, &Error{}) {
+			err = Error{File: *moduleURL, Phase: PhaseLoading, Err: err}
+		}
+		fail(err)
+		return
+	}
+	module := ModuleMeta{URL: moduleURL}
+	for _, importPath := range compiled.imports {
+		imported, err := e.resolveModule(e.viewResolver, module, importPath)
+		if err != nil {
+			fail(Error{File: *moduleURL, Phase: PhaseLoading, Err: err})
+			continue
+		}
+		if m, ok := e.registeredModule(imported); ok && isNativeModule(m) {
+			continue
+		}
+		enqueue(imported)
+	}
+}
+
+var importCall = regexp.MustCompile(`module\.do_import\('([^']*)'\)`)
+
+func findImports(jsCode string) []string {
+	var result []string
+	for _, match := range importCall.FindAllStringSubmatch(jsCode, -1) {
+		result = append(result, match[1])
+	}
+	return result
+}
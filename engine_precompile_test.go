@@ -0,0 +1,121 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+// countingResolver counts loaded module contents.
+type countingResolver struct {
+	wax.ViewResolver
+	loaded atomic.Int32
+}
+
+func (r *countingResolver) GetContent(u url.URL) (string, error) {
+	r.loaded.Add(1)
+	return r.ViewResolver.GetContent(u)
+}
+
+func (r *countingResolver) ListModules() ([]*url.URL, error) {
+	return r.ViewResolver.(wax.ModuleLister).ListModules()
+}
+
+func Test_Engine_Precompile(t *testing.T) {
+	fs := fstest.MapFS{
+		"Good.jsx": &fstest.MapFile{Data: []byte(`
+            import {Button} from "./parts/Button.jsx"
+            export function Good() { return <Button/> }`)},
+		"parts/Button.jsx": &fstest.MapFile{Data: []byte(`
+            import {label} from "../Label.jsx"
+            export function Button() { return <button>{label}</button> }`)},
+		"Label.jsx": &fstest.MapFile{Data: []byte(`export const label = "ok"`)},
+	}
+	broken := fstest.MapFS{
+		"Broken.jsx": &fstest.MapFile{Data: []byte(`
+            export function Broken() { return <div> }`)},
+		"MissingImport.jsx": &fstest.MapFile{Data: []byte(`
+            import {Nope} from "./Nope.jsx"
+            export function MissingImport() { return <Nope/> }`)},
+	}
+	for k, v := range fs {
+		broken[k] = v
+	}
+
+	t.Run("views_and_imports", func(t *testing.T) {
+		resolver := &countingResolver{ViewResolver: wax.NewFsViewResolver(fs)}
+		engine := wax.New(resolver)
+		if err := engine.Precompile(context.Background(), "Good"); err != nil {
+			t.Fatal(err)
+		}
+		if loaded := resolver.loaded.Load(); loaded != 3 {
+			t.Errorf("expected 3 modules to be loaded, got %d", loaded)
+		}
+
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "Good", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "views_and_imports", "<button>ok</button>", buf.String())
+		if loaded := resolver.loaded.Load(); loaded != 3 {
+			t.Errorf("render loaded modules again, loaded %d", loaded)
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		resolver := &countingResolver{ViewResolver: wax.NewFsViewResolver(broken)}
+		err := wax.New(resolver).PrecompileAll(context.Background())
+
+		var errs interface{ Unwrap() []error }
+		if !errors.As(err, &errs) || len(errs.Unwrap()) != 2 {
+			t.Fatalf("expected 2 errors, got %v", err)
+		}
+		for _, err := range errs.Unwrap() {
+			var waxError wax.Error
+			if !errors.As(err, &waxError) {
+				t.Errorf("expected wax.Error, got %v", err)
+			}
+		}
+		if loaded := resolver.loaded.Load(); loaded != 5 {
+			t.Errorf("expected 5 modules to be loaded, got %d", loaded)
+		}
+	})
+}
+
+func Test_Engine_PrecompileLayoutsAndGlobalScripts(t *testing.T) {
+	fs := fstest.MapFS{
+		"pages/Home.jsx": &fstest.MapFile{Data: []byte(`
+            export default function Home() { return <h1>{site.name}</h1> }`)},
+		"_layout.jsx": &fstest.MapFile{Data: []byte(`
+            import {Nav} from "./parts/Nav.jsx"
+            export default function Layout(p) { return <body><Nav/>{p.children}</body> }`)},
+		"parts/Nav.jsx": &fstest.MapFile{Data: []byte(`
+            export function Nav() { return <nav>nav</nav> }`)},
+		"globals.js": &fstest.MapFile{Data: []byte(`
+            globalThis.site = { name: "wax" }`)},
+	}
+	resolver := &countingResolver{ViewResolver: wax.NewFsViewResolver(fs)}
+	// global scripts are resolved from the views root, like in pooled runtimes
+	engine := wax.New(resolver, wax.WithLayoutFiles("_layout"), wax.WithGlobalScript("./globals.js"), wax.WithRuntimePool(1))
+	if err := engine.Precompile(context.Background(), "pages/Home"); err != nil {
+		t.Fatal(err)
+	}
+	if loaded := resolver.loaded.Load(); loaded != 4 {
+		t.Errorf("expected 4 modules to be loaded, got %d", loaded)
+	}
+
+	buf := bytes.NewBufferString("")
+	if err := engine.Render(buf, "pages/Home", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "layout", "<body><nav>nav</nav><h1>wax</h1></body>", buf.String())
+	if loaded := resolver.loaded.Load(); loaded != 4 {
+		t.Errorf("render loaded modules again, loaded %d", loaded)
+	}
+}
@@ -0,0 +1,47 @@
+package wax_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_Reexport(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.tsx": &fstest.MapFile{Data: []byte(`
+            import Card, { Button, Link, Badge, ui, icons } from "./components/index.ts"
+            import * as all from "./components/index.ts"
+            export function View() {
+                return <main>
+                    <Card/><Button/><Link/><Badge/><ui.Button/><icons.Check/>
+                    <p>{Object.keys(all).sort().join(",")}</p>
+                </main>
+            }`)},
+		"components/index.ts": &fstest.MapFile{Data: []byte(`
+            export * from "./buttons.tsx"
+            export * as ui from "./buttons.tsx"
+            export { Card as default, Badge } from "./card.tsx"
+            export { default as icons } from "./icons.tsx"
+            export function Link() { return <a>local</a> }`)},
+		"components/buttons.tsx": &fstest.MapFile{Data: []byte(`
+            export function Button() { return <button>button</button> }
+            export function Link() { return <a>button-link</a> }`)},
+		"components/card.tsx": &fstest.MapFile{Data: []byte(`
+            function Card() { return <section>card</section> }
+            const Badge = () => <b>badge</b>
+            export { Card, Badge }`)},
+		"components/icons.tsx": &fstest.MapFile{Data: []byte(`
+            const icons = { Check: () => <i>check</i> }
+            export default icons`)},
+	}
+
+	buf := bytes.NewBufferString("")
+	if err := wax.New(wax.NewFsViewResolver(fs)).Render(buf, "View", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "reexport",
+		`<main><section>card</section><button>button</button><a>local</a><b>badge</b><button>button</button><i>check</i><p>Badge,Button,Link,icons,ui</p></main>`,
+		buf.String())
+}
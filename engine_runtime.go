@@ -0,0 +1,382 @@
+package wax
+
+import (
+	"maps"
+	"net/url"
+	"strconv"
+
+	"github.com/dop251/goja"
+)
+
+// WithRuntimePool keeps up to size prepared runtimes and reuses them between renders.
+// Pooled runtimes run global scripts once, resolved relative to the views root with view resolver of the render.
+// Runtimes are reused only by renders with the same view resolver, when engine has global scripts.
+// Runtime is dropped when render changed objects created by global scripts (e.g. `config.items.push(x)`),
+// but variables of global scripts not reachable from globals or exports (closures) are not checked.
+func WithRuntimePool(size int) Option {
+	return func(e *Engine) {
+		if size > 0 {
+			e.pool = make(chan *jsRuntime, size)
+		} else {
+			e.pool = nil
+		}
+	}
+}
+
+type jsRuntime struct {
+	vm  *goja.Runtime
+	wax *waxJSObj
+
+	// viewResolver resolved global scripts of the runtime
+	viewResolver ViewResolver
+
+	baseModules map[string]goja.Value
+	baseGlobals map[string]goja.Value
+	baseSymbols map[*goja.Symbol]goja.Value
+	// ownSymbols is Object.getOwnPropertySymbols captured before views run
+	ownSymbols goja.Callable
+	// stateUnchanged reports whether built-in objects and objects created by global scripts are the same as after global scripts
+	stateUnchanged goja.Callable
+}
+
+var rootModuleURL = &url.URL{Scheme: "file", Path: "/"}
+
+func (e *Engine) acquireRuntime(viewModule ModuleMeta, context *runContext) (*jsRuntime, error) {
+	if e.pool == nil {
+		return e.newRuntime(viewModule, context)
+	}
+
+	var rt *jsRuntime
+	select {
+	case rt = <-e.pool:
+		if len(e.globalScripts) > 0 && !sameViewResolver(rt.viewResolver, context.ViewResolver) {
+			rt = nil
+		}
+	default:
+	}
+	if rt == nil {
+		var err error
+		rt, err = e.newRuntime(ModuleMeta{URL: rootModuleURL}, &runContext{ViewResolver: context.ViewResolver})
+		if err != nil {
+			return nil, err
+		}
+	}
+	rt.bind(context)
+	return rt, nil
+}
+
+// releaseRuntime returns runtime to the pool. Runtimes used by failed renders or which can't be reset are dropped.
+func (e *Engine) releaseRuntime(rt *jsRuntime, renderSucceeded bool) {
+	if e.pool == nil || !renderSucceeded {
+		return
+	}
+	rt.keepSharedModules()
+	if !rt.reset() {
+		return
+	}
+	select {
+	case e.pool <- rt:
+	default:
+	}
+}
+
+func (e *Engine) newRuntime(baseModule ModuleMeta, context *runContext) (*jsRuntime, error) {
+	vm := goja.New()
+	global := vm.GlobalObject()
+	builtins := global.GetOwnPropertyNames()
+	if e.limits.callStackSize > 0 {
+		vm.SetMaxCallStackSize(e.limits.callStackSize)
+	}
+	waxObj := newWaxObj(e, vm, context)
+	vm.GlobalObject().DefineDataProperty("wax", waxObj.obj, goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+
+	for k, v := range e.globals {
+		vm.GlobalObject().Set(k, waxObj.bindContext(v))
+	}
+	for k, v := range context.Globals {
+		vm.GlobalObject().Set(k, waxObj.bindContext(v))
+	}
+	beforeScripts := make(map[string]goja.Value)
+	for _, k := range global.GetOwnPropertyNames() {
+		beforeScripts[k] = global.Get(k)
+	}
+
+	for _, v := range e.globalScripts {
+		moduleURI, err := context.ViewResolver.ResolveModuleFile(baseModule, v)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = e.load(context, waxObj, moduleURI)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if e.pool == nil {
+		return &jsRuntime{vm: vm, wax: waxObj}, nil
+	}
+	rt := &jsRuntime{
+		vm:           vm,
+		wax:          waxObj,
+		viewResolver: context.ViewResolver,
+		baseModules:  maps.Clone(waxObj.modules),
+		baseGlobals:  make(map[string]goja.Value),
+		baseSymbols:  make(map[*goja.Symbol]goja.Value),
+	}
+	for _, k := range global.GetOwnPropertyNames() {
+		rt.baseGlobals[k] = global.Get(k)
+	}
+	rt.ownSymbols, _ = goja.AssertFunction(vm.Get("Object").ToObject(vm).Get("getOwnPropertySymbols"))
+	symbols, err := rt.globalSymbols()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range symbols {
+		rt.baseSymbols[s] = global.GetSymbol(s)
+	}
+	// objects set before global scripts are Go values, their state is not tracked
+	var roots, skip []any
+	for k, v := range rt.baseGlobals {
+		if before, ok := beforeScripts[k]; ok && before.SameAs(v) {
+			skip = append(skip, v)
+		} else {
+			roots = append(roots, v)
+		}
+	}
+	for key, module := range rt.baseModules {
+		if moduleURL, err := url.Parse(key); err == nil {
+			if m, ok := e.registeredModule(moduleURL); ok && isNativeModule(m) {
+				continue
+			}
+		}
+		roots = append(roots, moduleExports(module), module.(*goja.Object).Get("default"))
+	}
+	unchanged, err := stateSnapshot(vm, builtins, roots, skip)
+	if err != nil {
+		return nil, err
+	}
+	rt.stateUnchanged = unchanged
+	return rt, nil
+}
+
+// stateScript returns function comparing objects with their state at the time of the call.
+// Own properties of objects are compared, roots are compared with all objects they reach (properties, prototypes
+// and entries of Map and Set), except of objects to skip.
+// It uses functions captured before views run, so views can't change the way state is read.
+const stateScript = `(function (objects, roots, skip) {
+	const ownKeys = Reflect.ownKeys, describe = Reflect.getOwnPropertyDescriptor, prototypeOf = Reflect.getPrototypeOf,
+		isExtensible = Reflect.isExtensible, apply = Reflect.apply, is = Object.is, sort = Array.prototype.sort,
+		WeakSetType = WeakSet, weakAdd = WeakSet.prototype.add, weakHas = WeakSet.prototype.has,
+		mapEntries = Map.prototype.entries, mapNext = prototypeOf(new Map().entries()).next,
+		setValues = Set.prototype.values, setNext = prototypeOf(new Set().values()).next;
+	function isObject(v) {
+		return (typeof v === "object" && v !== null) || typeof v === "function";
+	}
+	function entries(o, next, iterator) {
+		const result = [];
+		try {
+			const it = apply(iterator, o, []);
+			for (let r = apply(next, it, []); !r.done; r = apply(next, it, [])) {
+				result[result.length] = r.value;
+			}
+		} catch (e) {
+			// not an instance
+		}
+		return result;
+	}
+	// keysOf returns own keys with sorted names, goja can add lazy properties of functions in other order
+	function keysOf(o) {
+		const keys = ownKeys(o), names = [], symbols = [];
+		for (let j = 0; j < keys.length; j++) {
+			if (typeof keys[j] === "symbol") {
+				symbols[symbols.length] = keys[j];
+			} else {
+				names[names.length] = keys[j];
+			}
+		}
+		apply(sort, names, []);
+		for (let j = 0; j < symbols.length; j++) {
+			names[names.length] = symbols[j];
+		}
+		return names;
+	}
+	function state() {
+		const s = [], seen = new WeakSetType(), queue = [];
+		function visit(v) {
+			if (isObject(v) && !apply(weakHas, seen, [v])) {
+				apply(weakAdd, seen, [v]);
+				queue[queue.length] = v;
+			}
+		}
+		function record(o, deep) {
+			s[s.length] = prototypeOf(o);
+			s[s.length] = isExtensible(o);
+			const keys = keysOf(o);
+			for (let j = 0; j < keys.length; j++) {
+				const d = describe(o, keys[j]);
+				s[s.length] = keys[j];
+				s[s.length] = d.value;
+				s[s.length] = d.get;
+				s[s.length] = d.set;
+				s[s.length] = d.writable;
+				if (deep) {
+					visit(d.value);
+					visit(d.get);
+					visit(d.set);
+				}
+			}
+			if (!deep) {
+				return;
+			}
+			visit(prototypeOf(o));
+			const mapped = entries(o, mapNext, mapEntries);
+			for (let j = 0; j < mapped.length; j++) {
+				s[s.length] = mapped[j][0];
+				s[s.length] = mapped[j][1];
+				visit(mapped[j][0]);
+				visit(mapped[j][1]);
+			}
+			const values = entries(o, setNext, setValues);
+			for (let j = 0; j < values.length; j++) {
+				s[s.length] = values[j];
+				visit(values[j]);
+			}
+		}
+		apply(weakAdd, seen, [globalThis]);
+		for (let i = 0; i < skip.length; i++) {
+			if (isObject(skip[i])) {
+				apply(weakAdd, seen, [skip[i]]);
+			}
+		}
+		for (let i = 0; i < objects.length; i++) {
+			apply(weakAdd, seen, [objects[i]]);
+			record(objects[i], false);
+		}
+		for (let i = 0; i < roots.length; i++) {
+			visit(roots[i]);
+		}
+		for (let i = 0; i < queue.length; i++) {
+			record(queue[i], true);
+		}
+		return s;
+	}
+	const base = state();
+	return function () {
+		const s = state();
+		if (s.length !== base.length) {
+			return false;
+		}
+		for (let i = 0; i < s.length; i++) {
+			if (!is(s[i], base[i])) {
+				return false;
+			}
+		}
+		return true;
+	};
+})`
+
+// stateSnapshot snapshots built-in globals (constructors, namespaces like Math and JSON), their prototypes
+// and objects reachable from roots.
+func stateSnapshot(vm *goja.Runtime, builtins []string, roots, skip []any) (goja.Callable, error) {
+	global := vm.GlobalObject()
+	var objects []any
+	for _, name := range builtins {
+		o, ok := global.Get(name).(*goja.Object)
+		if !ok || o.SameAs(global) {
+			continue
+		}
+		objects = append(objects, o)
+		if prototype, ok := o.Get("prototype").(*goja.Object); ok {
+			objects = append(objects, prototype)
+		}
+	}
+	snapshot, err := vm.RunString(stateScript)
+	if err != nil {
+		return nil, err
+	}
+	take, _ := goja.AssertFunction(snapshot)
+	unchanged, err := take(goja.Undefined(), vm.NewArray(objects...), vm.NewArray(roots...), vm.NewArray(skip...))
+	if err != nil {
+		return nil, err
+	}
+	f, _ := goja.AssertFunction(unchanged)
+	return f, nil
+}
+
+// globalSymbols returns all own symbol properties of global object, also not enumerable.
+func (rt *jsRuntime) globalSymbols() ([]*goja.Symbol, error) {
+	v, err := rt.ownSymbols(goja.Undefined(), rt.vm.GlobalObject())
+	if err != nil {
+		return nil, err
+	}
+	array := v.ToObject(rt.vm)
+	symbols := make([]*goja.Symbol, array.Get("length").ToInteger())
+	for i := range symbols {
+		symbols[i], _ = array.Get(strconv.Itoa(i)).(*goja.Symbol)
+	}
+	return symbols, nil
+}
+
+// sameViewResolver compares resolvers, resolvers of not comparable types are never the same.
+func sameViewResolver(a, b ViewResolver) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return a == b
+}
+
+func (rt *jsRuntime) bind(context *runContext) {
+	rt.wax.context = context
+	rt.wax.modules = maps.Clone(rt.baseModules)
+	for k, v := range context.Globals {
+		rt.vm.GlobalObject().Set(k, rt.wax.bindContext(v))
+	}
+}
+
+// reset restores global object of the runtime to the state after global scripts.
+// It returns false when runtime can't be reused: global can't be restored, built-in objects or objects
+// created by global scripts were modified.
+func (rt *jsRuntime) reset() bool {
+	rt.vm.ClearInterrupt()
+	rt.wax.context = nil
+	rt.wax.modules = nil
+
+	if unchanged, err := rt.stateUnchanged(goja.Undefined()); err != nil || !unchanged.ToBoolean() {
+		return false
+	}
+	global := rt.vm.GlobalObject()
+	for _, k := range global.GetOwnPropertyNames() {
+		if _, isBase := rt.baseGlobals[k]; !isBase {
+			if err := global.Delete(k); err != nil {
+				return false
+			}
+		}
+	}
+	symbols, err := rt.globalSymbols()
+	if err != nil {
+		return false
+	}
+	for _, s := range symbols {
+		if _, isBase := rt.baseSymbols[s]; !isBase {
+			if err := global.DeleteSymbol(s); err != nil {
+				return false
+			}
+		}
+	}
+	for k, v := range rt.baseGlobals {
+		if current := global.Get(k); current == nil || !current.SameAs(v) {
+			if err := global.Set(k, v); err != nil {
+				return false
+			}
+		}
+	}
+	for s, v := range rt.baseSymbols {
+		if current := global.GetSymbol(s); current == nil || !current.SameAs(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package wax
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithSharedModules marks modules (paths from views root, or names of registered modules) as shared.
+// Modules can mark themselves with "use shared" directive too.
+//
+// Shared module is evaluated once per pooled runtime and reused by later renders (see WithRuntimePool).
+// Its imports are shared as well. Exports of shared modules are frozen and objects they hold must not be mutated -
+// changes would leak to later renders. Module code runs with globals of the render that loaded it first,
+// so it should not use RunBinding.Globals or render context at top level.
+func WithSharedModules(paths ...string) Option {
+	return func(e *Engine) {
+		if e.sharedModules == nil {
+			e.sharedModules = make(map[string]bool)
+		}
+		for _, p := range paths {
+			e.sharedModules[strings.TrimPrefix(path.Clean("/"+p), "/")] = true
+		}
+	}
+}
+
+// sharedDirective matches "use shared" directive at module start, after hoisted function exports.
+var sharedDirective = regexp.MustCompile(`^(?:module\.exports\.\w+ = \w+;)*\s*(?:(?://[^\n]*|/\*[\s\S]*?\*/)\s*)*["']use shared["']`)
+
+func hasSharedDirective(jsCode string) bool {
+	return sharedDirective.MatchString(jsCode)
+}
+
+func (e *Engine) isSharedModule(moduleURL *url.URL, compiled *CompiledModule) bool {
+	return compiled.shared || e.sharedModules[moduleDisplayName(moduleURL)]
+}
+
+// freezeExports freezes exports object of loaded shared module.
+func (c *waxJSObj) freezeExports(module goja.Value) error {
+	freeze, _ := goja.AssertFunction(c.vm.GlobalObject().Get("Object").ToObject(c.vm).Get("freeze"))
+	_, err := freeze(goja.Undefined(), moduleExports(module))
+	return err
+}
+
+// keepSharedModules adds shared modules loaded by the last render to modules of the runtime.
+// Older versions of these modules are dropped.
+func (rt *jsRuntime) keepSharedModules() {
+	for _, key := range rt.wax.shared {
+		moduleURL, err := url.Parse(key)
+		if err != nil {
+			continue
+		}
+		name := moduleName(moduleURL)
+		for baseKey := range rt.baseModules {
+			if baseURL, err := url.Parse(baseKey); err == nil && moduleName(baseURL) == name {
+				delete(rt.baseModules, baseKey)
+			}
+		}
+		rt.baseModules[key] = rt.wax.modules[key]
+	}
+	rt.wax.shared = nil
+}
@@ -0,0 +1,64 @@
+package wax_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_SharedModules(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.tsx": &fstest.MapFile{Data: []byte(`
+            import { countries } from "./lib/countries.ts"
+            import { format } from "./lib/format.ts"
+            import { local } from "./lib/local.ts"
+            export function View() { return <p>{format(countries.length)}-{local}</p> }`)},
+		"Mutate.tsx": &fstest.MapFile{Data: []byte(`
+            import * as lib from "./lib/countries.ts"
+            export function Mutate() { lib.countries = []; return <p/> }`)},
+		"lib/countries.ts": &fstest.MapFile{Data: []byte(`
+            "use shared"
+            import { upper } from "./upper.ts"
+            counter.Evaluated("countries")
+            export const countries = ["pl", "de"].map(upper)`)},
+		"lib/upper.ts": &fstest.MapFile{Data: []byte(`
+            counter.Evaluated("upper")
+            export function upper(s) { return s.toUpperCase() }`)},
+		"lib/format.ts": &fstest.MapFile{Data: []byte(`
+            counter.Evaluated("format")
+            export function format(n) { return "n=" + n }`)},
+		"lib/local.ts": &fstest.MapFile{Data: []byte(`
+            counter.Evaluated("local")
+            export const local = "local"`)},
+	}
+	evaluated := map[string]int{}
+	engine := wax.New(wax.NewFsViewResolver(fs),
+		wax.WithRuntimePool(1),
+		wax.WithSharedModules("lib/format.ts"),
+		wax.WithGlobalObject("counter", map[string]any{"Evaluated": func(name string) { evaluated[name]++ }}),
+	)
+
+	for range 3 {
+		buf := bytes.NewBufferString("")
+		if err := engine.Render(buf, "View", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "view", "<p>n=2-local</p>", buf.String())
+	}
+	expected := map[string]int{"countries": 1, "upper": 1, "format": 1, "local": 3}
+	for name, count := range expected {
+		if evaluated[name] != count {
+			t.Errorf("module %s evaluated %d times, expected %d", name, evaluated[name], count)
+		}
+	}
+
+	t.Run("frozen", func(t *testing.T) {
+		err := engine.Render(bytes.NewBufferString(""), "Mutate", nil)
+		if err == nil || !strings.Contains(err.Error(), "TypeError") {
+			t.Errorf("expected TypeError on mutation of shared exports, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,101 @@
+package wax
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/dop251/goja"
+)
+
+// moduleWrapperPrefix is put before transpiled module code, on its first line.
+const moduleWrapperPrefix = ";(function (module) {;"
+
+func wrapModule(jsCode string, key string) string {
+	return fmt.Sprintf("%s%s;})(wax.GetModule('%s'));", moduleWrapperPrefix, jsCode, key)
+}
+
+func (e *Engine) transpile(fileName string, fileContent string) (string, *SourceMap, error) {
+	if e.transpileCacheDir != "" {
+		return e.cachedTranspile(fileName, fileContent)
+	}
+	return e.transpileSource(fileName, fileContent)
+}
+
+func (e *Engine) transpileSource(fileName string, fileContent string) (string, *SourceMap, error) {
+	if t, ok := e.transpiler.(SourceMapTranspiler); ok {
+		return t.TranspileWithSourceMap(fileName, fileContent)
+	}
+	jsCode, err := e.transpiler.Transpile(fileName, fileContent)
+	return jsCode, nil, err
+}
+
+var stackPosition = regexp.MustCompile(`([^\s()]+):(\d+):(\d+)`)
+
+// mapStackToSource rewrites positions of transpiled code in error stack to positions in original files.
+func (e *Engine) mapStackToSource(err error) error {
+	waxError, ok := err.(Error)
+	if !ok || waxError.Stack == "" {
+		return err
+	}
+	waxError.Stack = stackPosition.ReplaceAllStringFunc(waxError.Stack, func(position string) string {
+		match := stackPosition.FindStringSubmatch(position)
+		line, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		line, column, ok := e.originalPosition(match[1], line, column)
+		if !ok {
+			return position
+		}
+		if waxError.Line == 0 {
+			if sourceFile, err := url.Parse(match[1]); err == nil {
+				waxError.Line, waxError.Column, waxError.SourceFile = line, column, *sourceFile
+			}
+		}
+		return fmt.Sprintf("%s:%d:%d", match[1], line, column)
+	})
+	return waxError
+}
+
+func (e *Engine) originalPosition(key string, line, column int) (int, int, bool) {
+	moduleURL, err := url.Parse(key)
+	if err != nil {
+		return 0, 0, false
+	}
+	module, ok := e.cache.Peek(moduleURL)
+	if !ok {
+		return 0, 0, false
+	}
+	return sourcePosition(module.sourceMap, line, column)
+}
+
+// sourcePosition maps position in wrapped module code to position in original file.
+func sourcePosition(sourceMap *SourceMap, line, column int) (int, int, bool) {
+	if line == 1 {
+		column = max(column-len(moduleWrapperPrefix), 1)
+	}
+	if sourceMap == nil {
+		return line, column, true
+	}
+	return sourceMap.Original(line, column)
+}
+
+var syntaxErrorPosition = regexp.MustCompile(`Line (\d+):(\d+)`)
+
+// compileErrorPosition returns position of goja compile error in wrapped module code.
+// Parser reports position only in message.
+func compileErrorPosition(err error) (int, int, bool) {
+	var compilerError *goja.CompilerSyntaxError
+	if errors.As(err, &compilerError) && compilerError.File != nil {
+		position := compilerError.File.Position(compilerError.Offset)
+		return position.Line, position.Column, true
+	}
+	match := syntaxErrorPosition.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0, 0, false
+	}
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+	return line, column, true
+}
@@ -0,0 +1,91 @@
+package wax_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_SourceMap(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`import {Foo} from "./Foo.jsx"
+export function View(p) {
+    return <div class="first"><b>{p.title}</b><i>{p.missing.value}</i></div>
+}`)},
+		"FirstLine.jsx": &fstest.MapFile{Data: []byte(`export function FirstLine(p) { return <div class="first"><b>{p.title}</b><i>{p.missing.value}</i></div> }`)},
+		"Foo.jsx":       &fstest.MapFile{Data: []byte(`export const Foo = 1`)},
+	}
+
+	checks := []struct {
+		view   string
+		line   int
+		column int
+	}{
+		{view: "View", line: 3, column: 61},
+		{view: "FirstLine", line: 1, column: 88},
+	}
+	for _, check := range checks {
+		t.Run(check.view, func(t *testing.T) {
+			err := wax.New(wax.NewFsViewResolver(fs)).Render(bytes.NewBufferString(""), check.view, map[string]any{"title": "x"})
+
+			var waxError wax.Error
+			if !errors.As(err, &waxError) {
+				t.Fatalf("expected wax.Error, got %v", err)
+			}
+			if waxError.Line != check.line || waxError.Column != check.column {
+				t.Errorf("invalid position > \n\tgot      : %d:%d\n\texpected : %d:%d", waxError.Line, waxError.Column, check.line, check.column)
+			}
+			source := strings.Split(string(fs[check.view+".jsx"].Data), "\n")[check.line-1]
+			if !strings.HasPrefix(source[check.column-1:], "value") {
+				t.Errorf("position does not point to failing expression: %q", source[check.column-1:])
+			}
+			if !strings.Contains(waxError.Stack, check.view+".jsx") {
+				t.Errorf("stack does not point to view file: %s", waxError.Stack)
+			}
+		})
+	}
+}
+
+func Test_Engine_SourceMap_SourceFile(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`import {format} from "./lib/format.jsx"
+export function View(p) {
+    return <div>{format(p)}</div>
+}`)},
+		"lib/format.jsx": &fstest.MapFile{Data: []byte(`export function format(p) {
+    return <b>{p.missing.value}</b>
+}`)},
+		"Duplicated.jsx": &fstest.MapFile{Data: []byte(`export function Duplicated(p) {
+    const title = p.title
+    const title = "other"
+    return <div>{title}</div>
+}`)},
+	}
+	engine := wax.New(wax.NewFsViewResolver(fs))
+
+	checks := []struct {
+		view   string
+		file   string
+		line   int
+		column int
+	}{
+		{view: "View", file: "lib/format.jsx", line: 2, column: 26},
+		{view: "Duplicated", file: "Duplicated.jsx", line: 3, column: 11},
+	}
+	for _, check := range checks {
+		t.Run(check.view, func(t *testing.T) {
+			err := engine.Render(bytes.NewBufferString(""), check.view, map[string]any{"title": "x"})
+			var waxError wax.Error
+			if !errors.As(err, &waxError) {
+				t.Fatalf("expected wax.Error, got %v", err)
+			}
+			if waxError.SourceFile.Path != "/"+check.file || waxError.Line != check.line || waxError.Column != check.column {
+				t.Errorf("invalid position > \n\tgot      : %s:%d:%d\n\texpected : /%s:%d:%d", waxError.SourceFile.Path, waxError.Line, waxError.Column, check.file, check.line, check.column)
+			}
+		})
+	}
+}
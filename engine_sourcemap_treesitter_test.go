@@ -0,0 +1,31 @@
+//go:build !wax_no_treesitter
+
+package wax_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_SourceMap_JSON(t *testing.T) {
+	transpiler := wax.NewTreeSitterTranspiler().(wax.SourceMapTranspiler)
+	_, sourceMap, err := transpiler.TranspileWithSourceMap("View.jsx", "export function View() {\n  return <div>{1}</div>\n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(sourceMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &wax.SourceMap{}
+	if err := json.Unmarshal(data, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sourceMap, decoded) {
+		t.Errorf("source map changed after JSON round trip:\n\tgot      : %v\n\texpected : %v", decoded, sourceMap)
+	}
+}
@@ -0,0 +1,139 @@
+package wax
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// WithFlushAfterHead flushes output after document </head> is written.
+func WithFlushAfterHead() Option {
+	return func(e *Engine) {
+		e.streaming.flushAfterHead = true
+	}
+}
+
+// WithOutOfOrderStreaming renders <wax.Deferred> fallback in place and sends its children
+// as trailing chunks after the page. Without it children are rendered in place.
+func WithOutOfOrderStreaming() Option {
+	return func(e *Engine) {
+		e.streaming.outOfOrder = true
+	}
+}
+
+type streamOptions struct {
+	flushAfterHead bool
+	outOfOrder     bool
+}
+
+type streamState struct {
+	streamOptions
+	deferred     []*deferredContent
+	nextID       int
+	helperIsSent bool
+}
+
+type (
+	flushMarker     struct{}
+	deferredContent struct {
+		id       int
+		fallback goja.Value
+		children goja.Value
+	}
+)
+
+var (
+	reflectTypeFlushMarker     = reflect.TypeOf(flushMarker{})
+	reflectTypeDeferredContent = reflect.TypeOf((*deferredContent)(nil))
+)
+
+func (c *waxJSObj) flush(fc goja.FunctionCall) goja.Value {
+	return c.vm.ToValue(flushMarker{})
+}
+
+func (c *waxJSObj) deferred(fc goja.FunctionCall) goja.Value {
+	props := fc.Argument(0).ToObject(c.vm)
+	return c.vm.ToValue(&deferredContent{
+		fallback: props.Get("fallback"),
+		children: props.Get("children"),
+	})
+}
+
+func flush(out io.Writer) error {
+	switch f := out.(type) {
+	case http.Flusher:
+		f.Flush()
+	case interface{ Flush() error }:
+		return f.Flush()
+	}
+	return nil
+}
+
+func (w *waxWriter) Flush() {
+	if w.err != nil || w.buf != nil {
+		return
+	}
+	if err := flush(w.out); err != nil {
+		w.err = err
+		w.vm.Interrupt(err)
+	}
+}
+
+func (w *waxWriter) writeHTMLWithFlushAfterHead(v string) bool {
+	if w.stream == nil || !w.stream.flushAfterHead {
+		return false
+	}
+	i := strings.Index(v, "</head>")
+	if i < 0 {
+		return false
+	}
+	i += len("</head>")
+	w.stream.flushAfterHead = false
+	w.WriteRaw(v[:i])
+	w.Flush()
+	w.WriteRaw(v[i:])
+	return true
+}
+
+func (w *waxWriter) writeDeferred(d *deferredContent) {
+	if w.stream == nil || !w.stream.outOfOrder {
+		w.process(d.children, w.vm)
+		return
+	}
+	w.stream.nextID++
+	d.id = w.stream.nextID
+	w.stream.deferred = append(w.stream.deferred, d)
+
+	w.WriteRaw(`<wax-placeholder id="wax-p-` + strconv.Itoa(d.id) + `">`)
+	w.process(d.fallback, w.vm)
+	w.WriteRaw(`</wax-placeholder>`)
+}
+
+const deferredSwapHelper = `<script>function waxSwap(i){var t=document.getElementById("wax-c-"+i),p=document.getElementById("wax-p-"+i);if(t&&p){p.replaceWith(t.content);t.remove()}}</script>`
+
+// writeDeferredChunks sends content of <wax.Deferred> elements after the page.
+func (w *waxWriter) writeDeferredChunks() {
+	if w.stream == nil {
+		return
+	}
+	for len(w.stream.deferred) > 0 && w.err == nil {
+		d := w.stream.deferred[0]
+		w.stream.deferred = w.stream.deferred[1:]
+
+		if !w.stream.helperIsSent {
+			w.Flush()
+			w.WriteRaw(deferredSwapHelper)
+			w.stream.helperIsSent = true
+		}
+		id := strconv.Itoa(d.id)
+		w.WriteRaw(`<template id="wax-c-` + id + `">`)
+		w.process(d.children, w.vm)
+		w.settle()
+		w.WriteRaw(`</template><script>waxSwap(` + id + `)</script>`)
+		w.Flush()
+	}
+}
@@ -0,0 +1,92 @@
+package wax_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+// flushRecorder records content written before each flush.
+type flushRecorder struct {
+	bytes.Buffer
+	flushed []string
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed = append(r.flushed, r.String())
+}
+
+func Test_Engine_Streaming(t *testing.T) {
+	fs := fstest.MapFS{
+		"Page.jsx": &fstest.MapFile{Data: []byte(`
+            export function Page() {
+                return <html>
+                    <head><title>t</title></head>
+                    <body>
+                        <header>h</header>
+                        <wax.Flush/>
+                        <wax.Deferred fallback={<i>loading</i>}>
+                            <b>slow</b>
+                        </wax.Deferred>
+                        <footer>f</footer>
+                    </body>
+                </html>
+            }`)},
+	}
+
+	checks := []struct {
+		name     string
+		options  []wax.Option
+		expected string
+		flushed  []string
+	}{
+		{
+			name:     "inline",
+			expected: `<html><head><title>t</title></head><body><header>h</header><b>slow</b><footer>f</footer></body></html>`,
+			flushed: []string{
+				`<html><head><title>t</title></head><body><header>h</header>`,
+			},
+		},
+		{
+			name:     "flush_after_head",
+			options:  []wax.Option{wax.WithFlushAfterHead()},
+			expected: `<html><head><title>t</title></head><body><header>h</header><b>slow</b><footer>f</footer></body></html>`,
+			flushed: []string{
+				`<html><head><title>t</title></head>`,
+				`<html><head><title>t</title></head><body><header>h</header>`,
+			},
+		},
+		{
+			name:    "out_of_order",
+			options: []wax.Option{wax.WithOutOfOrderStreaming()},
+			expected: `<html><head><title>t</title></head><body><header>h</header>` +
+				`<wax-placeholder id="wax-p-1"><i>loading</i></wax-placeholder><footer>f</footer></body></html>` +
+				`<script>function waxSwap(i){var t=document.getElementById("wax-c-"+i),p=document.getElementById("wax-p-"+i);if(t&&p){p.replaceWith(t.content);t.remove()}}</script>` +
+				`<template id="wax-c-1"><b>slow</b></template><script>waxSwap(1)</script>`,
+			flushed: []string{
+				`<html><head><title>t</title></head><body><header>h</header>`,
+				`<html><head><title>t</title></head><body><header>h</header>` +
+					`<wax-placeholder id="wax-p-1"><i>loading</i></wax-placeholder><footer>f</footer></body></html>`,
+			},
+		},
+	}
+	for _, check := range checks {
+		t.Run(check.name, func(t *testing.T) {
+			engine := wax.New(wax.NewFsViewResolver(fs), check.options...)
+			out := &flushRecorder{}
+			if err := engine.Render(out, "Page", nil); err != nil {
+				t.Fatal(err)
+			}
+			compareHTML(t, check.name, check.expected, out.String())
+
+			if len(out.flushed) < len(check.flushed) {
+				t.Fatalf("expected at least %d flushes, got %d", len(check.flushed), len(out.flushed))
+			}
+			for i, expected := range check.flushed {
+				compareHTML(t, check.name, expected, out.flushed[i])
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package wax
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// VersionedTranspiler is implemented by transpilers reporting version of their output.
+// Disk cache entries of other versions are not used.
+type VersionedTranspiler interface {
+	Version() string
+}
+
+// WithTranspileCacheDir stores transpiled modules in dir and reuses them after restart.
+// Entries are keyed by module content, file extension and transpiler version. Unreadable entries are rebuilt.
+func WithTranspileCacheDir(dir string) Option {
+	return func(e *Engine) {
+		e.transpileCacheDir = dir
+	}
+}
+
+type transpileCacheEntry struct {
+	Version    string     `json:"version"`
+	SourceHash string     `json:"sourceHash"`
+	Code       string     `json:"code"`
+	SourceMap  *SourceMap `json:"sourceMap,omitempty"`
+}
+
+func transpilerVersion(t TypeScriptTranspiler) string {
+	if v, ok := t.(VersionedTranspiler); ok {
+		return v.Version()
+	}
+	return fmt.Sprintf("%T", t)
+}
+
+func (e *Engine) cachedTranspile(fileName string, fileContent string) (string, *SourceMap, error) {
+	version := transpilerVersion(e.transpiler)
+	sourceHash := sha256.Sum256([]byte(fileContent))
+	// extension picks grammar, the same content can be transpiled differently
+	entryKey := sha256.Sum256([]byte(version + "\x00" + fileExt(fileName) + "\x00" + string(sourceHash[:])))
+	entryPath := filepath.Join(e.transpileCacheDir, hex.EncodeToString(entryKey[:])+".json")
+
+	if entry, ok := readTranspileCacheEntry(entryPath); ok &&
+		entry.Version == version && entry.SourceHash == hex.EncodeToString(sourceHash[:]) {
+		if entry.SourceMap != nil {
+			entry.SourceMap.File = fileName
+		}
+		return entry.Code, entry.SourceMap, nil
+	}
+
+	code, sourceMap, err := e.transpileSource(fileName, fileContent)
+	if err != nil {
+		return "", nil, err
+	}
+	// cache is optional, module is fine even if it could not be stored
+	_ = writeTranspileCacheEntry(entryPath, transpileCacheEntry{
+		Version:    version,
+		SourceHash: hex.EncodeToString(sourceHash[:]),
+		Code:       code,
+		SourceMap:  sourceMap,
+	})
+	return code, sourceMap, nil
+}
+
+// fileExt returns extension of file name or module URL.
+func fileExt(fileName string) string {
+	if u, err := url.Parse(fileName); err == nil && u.Path != "" {
+		fileName = u.Path
+	}
+	return path.Ext(fileName)
+}
+
+func readTranspileCacheEntry(path string) (transpileCacheEntry, bool) {
+	var entry transpileCacheEntry
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+func writeTranspileCacheEntry(path string, entry transpileCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,80 @@
+package wax_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_Engine_TranspileCacheDir(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.jsx": &fstest.MapFile{Data: []byte(`export function View() { return <b>from source</b> }`)},
+	}
+	dir := t.TempDir()
+	render := func(expected string) {
+		t.Helper()
+		buf := bytes.NewBufferString("")
+		engine := wax.New(wax.NewFsViewResolver(fs), wax.WithTranspileCacheDir(dir))
+		if err := engine.Render(buf, "View", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, expected, expected, buf.String())
+	}
+
+	render("<b>from source</b>")
+	entries, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 cache entry, got %d", len(entries))
+	}
+	entryPath := entries[0]
+
+	t.Run("reused", func(t *testing.T) {
+		var entry map[string]any
+		data, _ := os.ReadFile(entryPath)
+		if err := json.Unmarshal(data, &entry); err != nil {
+			t.Fatal(err)
+		}
+		entry["code"] = strings.ReplaceAll(entry["code"].(string), "from source", "from cache")
+		data, _ = json.Marshal(entry)
+		os.WriteFile(entryPath, data, 0o644)
+
+		render("<b>from cache</b>")
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		os.WriteFile(entryPath, []byte(`{"version":`), 0o644)
+
+		render("<b>from source</b>")
+		data, _ := os.ReadFile(entryPath)
+		if !json.Valid(data) {
+			t.Errorf("corrupted entry was not rebuilt: %s", data)
+		}
+	})
+	t.Run("extensions", func(t *testing.T) {
+		// the same content of .ts and .tsx file is parsed with different grammars
+		same := []byte(`export const label = (v: string) => v.toUpperCase()`)
+		fs := fstest.MapFS{
+			"Both.tsx": &fstest.MapFile{Data: []byte(`
+                import { label as a } from "./a.ts"
+                import { label as b } from "./b.tsx"
+                export function Both() { return <b>{a("ts")} {b("tsx")}</b> }`)},
+			"a.ts":  &fstest.MapFile{Data: same},
+			"b.tsx": &fstest.MapFile{Data: same},
+		}
+		dir := t.TempDir()
+		buf := bytes.NewBufferString("")
+		if err := wax.New(wax.NewFsViewResolver(fs), wax.WithTranspileCacheDir(dir)).Render(buf, "Both", nil); err != nil {
+			t.Fatal(err)
+		}
+		compareHTML(t, "extensions", "<b>TS TSX</b>", buf.String())
+		if entries, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(entries) != 3 {
+			t.Errorf("expected entry for each file, got %d", len(entries))
+		}
+	})
+}
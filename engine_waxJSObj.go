@@ -1,6 +1,7 @@
 package wax
 
 import (
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -13,6 +14,11 @@ type waxJSObj struct {
 	vm      *goja.Runtime
 	modules map[string]goja.Value
 	obj     goja.Value
+	// loading are modules being run, from the first imported
+	loading []*url.URL
+	// shared are keys of shared modules loaded by current render, sharedDepth counts shared modules being run
+	shared      []string
+	sharedDepth int
 }
 
 func newWaxObj(engine *Engine, vm *goja.Runtime, context *runContext) *waxJSObj {
@@ -28,6 +34,8 @@ func newWaxObj(engine *Engine, vm *goja.Runtime, context *runContext) *waxJSObj
 	o.Set("Raw", vm.ToValue(ret.raw))
 	o.Set("Now", vm.ToValue(ret.now))
 	o.Set("GetModule", vm.ToValue(ret.getModule))
+	o.Set("Flush", vm.ToValue(ret.flush))
+	o.Set("Deferred", vm.ToValue(ret.deferred))
 	return ret
 }
 
@@ -79,19 +87,51 @@ func buildModuleObjStruct(m *ModuleMeta, c *waxJSObj) *goja.Object {
 		"do_import": func(arg goja.FunctionCall) goja.Value {
 			v := arg.Arguments[0].String()
 
-			p, err := c.engine.viewResolver.ResolveModuleFile(*m, v)
+			m, err := c.importModule(m, v)
 			if err != nil {
 				c.vm.Interrupt(err)
 				return nil
 			}
+			return m
+		},
+		"do_import_dynamic": func(arg goja.FunctionCall) goja.Value {
+			v := arg.Argument(0).String()
 
-			m, err := c.engine.load(c.context, c, p)
+			promise, resolve, reject := c.vm.NewPromise()
+			imported, err := c.importModule(m, v)
 			if err != nil {
-				c.vm.Interrupt(err)
-				return nil
+				reject(c.vm.NewGoError(err))
+			} else {
+				resolve(c.namespace(imported))
 			}
-			return m
+			return c.vm.ToValue(promise)
 		},
 	}
-	return c.vm.ToValue(module).(*goja.Object)
+	// JS object keeps values assigned by module as they are, Go map would export them (e.g. default export object to map)
+	result := c.vm.NewObject()
+	for k, v := range module {
+		result.Set(k, v)
+	}
+	return result
+}
+
+func (c *waxJSObj) importModule(from *ModuleMeta, importPath string) (goja.Value, error) {
+	p, err := c.engine.resolveModule(c.engine.viewResolver, *from, importPath)
+	if err != nil {
+		return nil, err
+	}
+	return c.engine.load(c.context, c, p)
+}
+
+// namespace returns module namespace object, result of dynamic import - exports with default export.
+func (c *waxJSObj) namespace(module goja.Value) *goja.Object {
+	result := c.vm.NewObject()
+	exports := moduleExports(module)
+	for _, k := range exports.Keys() {
+		result.Set(k, exports.Get(k))
+	}
+	if d := module.(*goja.Object).Get("default"); d != nil && !goja.IsUndefined(d) {
+		result.Set("default", d)
+	}
+	return result
 }
@@ -1,6 +1,7 @@
 package wax
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"html/template"
@@ -19,9 +20,16 @@ import (
 type templateResult string
 
 type waxWriter struct {
-	jsObj goja.Value
-	vm    *goja.Runtime
-	out   io.Writer
+	jsObj  goja.Value
+	vm     *goja.Runtime
+	out    io.Writer
+	err    error
+	stream *streamState
+
+	loop     *eventLoop
+	parts    []*asyncPart
+	insertAt int
+	buf      *bytes.Buffer
 }
 
 func newWriter(out io.Writer, vm *goja.Runtime) *waxWriter {
@@ -56,6 +64,12 @@ func (w *waxWriter) process(arg goja.Value, vm *goja.Runtime) {
 			w.process(v, vm)
 			return true
 		})
+	case reflectTypeFlushMarker:
+		w.Flush()
+	case reflectTypeDeferredContent:
+		w.writeDeferred(arg.Export().(*deferredContent))
+	case reflectTypePromise:
+		w.writePromise(arg.Export().(*goja.Promise))
 	default:
 		w.callSub(arg)
 	}
@@ -73,7 +87,17 @@ func (w *waxWriter) callSub(arg goja.Value) {
 }
 
 func (w *waxWriter) WriteRaw(v string) {
-	io.WriteString(w.out, v)
+	if w.err != nil {
+		return
+	}
+	if w.buf != nil {
+		w.buf.WriteString(v)
+		return
+	}
+	if _, err := io.WriteString(w.out, v); err != nil {
+		w.err = err
+		w.vm.Interrupt(err)
+	}
 }
 
 func (w *waxWriter) writeHTML(fc goja.FunctionCall) goja.Value {
@@ -116,6 +140,9 @@ func (w *waxWriter) writeAttributes(fc goja.FunctionCall) goja.Value {
 }
 
 func (w *waxWriter) WriteHTML(v string) {
+	if w.writeHTMLWithFlushAfterHead(v) {
+		return
+	}
 	w.WriteRaw(v)
 }
 
@@ -0,0 +1,7 @@
+//go:build !wax_no_treesitter
+
+package wax
+
+func defaultTranspiler() TypeScriptTranspiler {
+	return NewTreeSitterTranspiler()
+}
@@ -0,0 +1,16 @@
+//go:build wax_no_treesitter
+
+package wax
+
+import "errors"
+
+// Built without tree-sitter (wax_no_treesitter tag) - views must come from bundle (see NewBundleViewResolver).
+func defaultTranspiler() TypeScriptTranspiler {
+	return noTranspiler{}
+}
+
+type noTranspiler struct{}
+
+func (noTranspiler) Transpile(fileName string, fileContent string) (string, error) {
+	return "", errors.New("built without transpiler (wax_no_treesitter), use views bundle")
+}
@@ -4,6 +4,7 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"unicode"
 )
 
 var voidElements = []string{
@@ -31,6 +32,14 @@ func isVoidElement(name string) bool {
 	return slices.Contains(voidElements, name)
 }
 
+// isComponentName reports if JSX tag is a component - capitalized name or member expression (<wax.Flush/>).
+func isComponentName(identifier string) bool {
+	if len(identifier) == 0 {
+		return false
+	}
+	return unicode.IsUpper([]rune(identifier)[0]) || strings.Contains(identifier, ".")
+}
+
 type importClause struct {
 	ImportedDefaultBinding string
 	NameSpaceImport        string
@@ -0,0 +1,245 @@
+package wax
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// SourceMapTranspiler is implemented by transpilers able to map transpiled code back to the original file.
+type SourceMapTranspiler interface {
+	TypeScriptTranspiler
+	TranspileWithSourceMap(fileName string, fileContent string) (string, *SourceMap, error)
+}
+
+// SourceMap maps positions in transpiled code to positions in original file.
+// Lines and columns in Mappings are zero-based, columns are counted in bytes.
+type SourceMap struct {
+	File     string
+	Mappings []SourceMapping
+}
+
+type SourceMapping struct {
+	GeneratedLine   int
+	GeneratedColumn int
+	OriginalLine    int
+	OriginalColumn  int
+}
+
+// Original returns position in original file for one-based line and column of transpiled code.
+func (m *SourceMap) Original(line, column int) (int, int, bool) {
+	line, column = line-1, column-1
+	i := sort.Search(len(m.Mappings), func(i int) bool {
+		mapping := m.Mappings[i]
+		return mapping.GeneratedLine > line || (mapping.GeneratedLine == line && mapping.GeneratedColumn > column)
+	})
+	if i == 0 || m.Mappings[i-1].GeneratedLine != line {
+		return 0, 0, false
+	}
+	mapping := m.Mappings[i-1]
+	return mapping.OriginalLine + 1, mapping.OriginalColumn + column - mapping.GeneratedColumn + 1, true
+}
+
+type sourceMapJSON struct {
+	Version  int      `json:"version"`
+	File     string   `json:"file,omitempty"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// MarshalJSON encodes map in source map v3 format.
+func (m *SourceMap) MarshalJSON() ([]byte, error) {
+	var mappings strings.Builder
+	var prev SourceMapping
+	line, lineStart := 0, true
+	for _, mapping := range m.Mappings {
+		for ; line < mapping.GeneratedLine; line++ {
+			mappings.WriteByte(';')
+			prev.GeneratedColumn = 0
+			lineStart = true
+		}
+		if !lineStart {
+			mappings.WriteByte(',')
+		}
+		lineStart = false
+		writeVLQ(&mappings, mapping.GeneratedColumn-prev.GeneratedColumn)
+		writeVLQ(&mappings, 0)
+		writeVLQ(&mappings, mapping.OriginalLine-prev.OriginalLine)
+		writeVLQ(&mappings, mapping.OriginalColumn-prev.OriginalColumn)
+		prev = mapping
+	}
+	return json.Marshal(sourceMapJSON{
+		Version:  3,
+		File:     m.File,
+		Sources:  []string{m.File},
+		Names:    []string{},
+		Mappings: mappings.String(),
+	})
+}
+
+func (m *SourceMap) UnmarshalJSON(data []byte) error {
+	var raw sourceMapJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Version != 3 {
+		return errors.New("unsupported source map version")
+	}
+	m.File = raw.File
+	m.Mappings = nil
+
+	var prev SourceMapping
+	for line, segments := range strings.Split(raw.Mappings, ";") {
+		prev.GeneratedColumn = 0
+		if segments == "" {
+			continue
+		}
+		for _, segment := range strings.Split(segments, ",") {
+			fields, err := readVLQ(segment)
+			if err != nil {
+				return err
+			}
+			if len(fields) < 4 {
+				continue
+			}
+			prev.GeneratedLine = line
+			prev.GeneratedColumn += fields[0]
+			prev.OriginalLine += fields[2]
+			prev.OriginalColumn += fields[3]
+			m.Mappings = append(m.Mappings, prev)
+		}
+	}
+	return nil
+}
+
+const vlqChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+func writeVLQ(sb *strings.Builder, v int) {
+	if v < 0 {
+		v = (-v << 1) | 1
+	} else {
+		v <<= 1
+	}
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		sb.WriteByte(vlqChars[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
+
+func readVLQ(segment string) ([]int, error) {
+	var result []int
+	v, shift := 0, 0
+	for i := 0; i < len(segment); i++ {
+		digit := strings.IndexByte(vlqChars, segment[i])
+		if digit < 0 {
+			return nil, errors.New("invalid source map mappings")
+		}
+		v += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if v&1 != 0 {
+			result = append(result, -(v >> 1))
+		} else {
+			result = append(result, v>>1)
+		}
+		v, shift = 0, 0
+	}
+	return result, nil
+}
+
+// transpiledOutput collects transpiled code. Code copied from source is mapped to its origin,
+// inserted code is mapped to the current position in source.
+type transpiledOutput struct {
+	strings.Builder
+	source     []byte
+	lineStarts []int
+	current    *uint32
+	line       int
+	column     int
+	mappings   []SourceMapping
+}
+
+func newTranspiledOutput(source []byte, current *uint32) *transpiledOutput {
+	result := &transpiledOutput{
+		source:     source,
+		lineStarts: []int{0},
+		current:    current,
+	}
+	for i, c := range source {
+		if c == '\n' {
+			result.lineStarts = append(result.lineStarts, i+1)
+		}
+	}
+	result.Grow(len(source) + 500)
+	return result
+}
+
+// copy writes source[from:to].
+func (o *transpiledOutput) copy(from, to uint32) {
+	chunk := o.source[from:to]
+	o.mapTo(int(from))
+	for {
+		i := bytes.IndexByte(chunk, '\n')
+		if i < 0 {
+			o.Builder.Write(chunk)
+			o.column += len(chunk)
+			return
+		}
+		o.Builder.Write(chunk[:i+1])
+		o.line, o.column = o.line+1, 0
+		from += uint32(i + 1)
+		chunk = chunk[i+1:]
+		if len(chunk) > 0 {
+			o.mapTo(int(from))
+		}
+	}
+}
+
+func (o *transpiledOutput) WriteString(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	o.mapTo(int(*o.current))
+	n, err := o.Builder.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		o.line += strings.Count(s, "\n")
+		o.column = len(s) - i - 1
+	} else {
+		o.column += len(s)
+	}
+	return n, err
+}
+
+func (o *transpiledOutput) mapTo(offset int) {
+	line := sort.SearchInts(o.lineStarts, offset+1) - 1
+	mapping := SourceMapping{
+		GeneratedLine:   o.line,
+		GeneratedColumn: o.column,
+		OriginalLine:    line,
+		OriginalColumn:  offset - o.lineStarts[line],
+	}
+	if n := len(o.mappings); n > 0 && o.mappings[n-1].GeneratedLine == o.line && o.mappings[n-1].GeneratedColumn == o.column {
+		o.mappings[n-1] = mapping
+		return
+	}
+	o.mappings = append(o.mappings, mapping)
+}
+
+func (o *transpiledOutput) sourceMap(fileName string) *SourceMap {
+	return &SourceMap{
+		File:     fileName,
+		Mappings: o.mappings,
+	}
+}
@@ -1,9 +1,10 @@
+//go:build !wax_no_treesitter
+
 package wax
 
 import (
 	"fmt"
 	"strings"
-	"unicode"
 
 	sitter "github.com/smacker/go-tree-sitter"
 	typescript "github.com/tree-sitter/tree-sitter-typescript/bindings/go"
@@ -31,11 +32,51 @@ type (
 // https://raw.githubusercontent.com/tree-sitter/tree-sitter-typescript/refs/heads/master/tsx/src/grammar.json
 var language = sitter.NewLanguage(typescript.LanguageTSX())
 
+// scriptLanguage parses TypeScript files - in TSX grammar `<T>value` type assertion is JSX element.
+// Files which fail to parse with it are parsed with TSX grammar, as JSX in .ts files worked before.
+var scriptLanguage = sitter.NewLanguage(typescript.LanguageTypescript())
+
+// languageOf picks grammar by extension of file name (or module URL). JS files can contain JSX.
+func languageOf(fileName string) *sitter.Language {
+	switch fileExt(fileName) {
+	case ".ts", ".mts", ".cts":
+		return scriptLanguage
+	}
+	return language
+}
+
+// Version changes whenever transpiled output changes.
+func (t *treeSitterTranspiler) Version() string {
+	return treeSitterTranspilerVersion
+}
+
+const treeSitterTranspilerVersion = "tree-sitter/7"
+
 func (t *treeSitterTranspiler) Transpile(fileName string, fileContent string) (string, error) {
+	result, _, err := t.TranspileWithSourceMap(fileName, fileContent)
+	return result, err
+}
+
+func (t *treeSitterTranspiler) TranspileWithSourceMap(fileName string, fileContent string) (string, *SourceMap, error) {
+	lang := languageOf(fileName)
+	tree := parse(lang, fileContent)
+	if lang == scriptLanguage && tree.RootNode().HasError() {
+		tree = parse(language, fileContent)
+	}
+
+	visitor := &treeSitterVisitor{}
+
+	for _, option := range t.options {
+		option(visitor)
+	}
+	return visitor.process(tree, fileName, fileContent)
+}
+
+func parse(lang *sitter.Language, fileContent string) *sitter.Tree {
 	source := strings.NewReader(fileContent)
 
 	parser := sitter.NewParser()
-	parser.SetLanguage(language)
+	parser.SetLanguage(lang)
 	defer parser.Close()
 	var buf [4096]byte
 	input := sitter.Input{
@@ -46,23 +87,16 @@ func (t *treeSitterTranspiler) Transpile(fileName string, fileContent string) (s
 
 		Encoding: sitter.InputEncodingUTF8,
 	}
-	tree := parser.ParseInput(nil, input)
-
-	visitor := &treeSitterVisitor{}
-
-	for _, option := range t.options {
-		option(visitor)
-	}
-	return visitor.process(tree, fileName, fileContent)
+	return parser.ParseInput(nil, input)
 }
 
 type treeSitterVisitor struct {
-	out   *strings.Builder
+	out   *transpiledOutput
 	last  uint32
 	debug bool
 }
 
-func (t *treeSitterVisitor) process(tree *sitter.Tree, fileName string, fileContent string) (string, error) {
+func (t *treeSitterVisitor) process(tree *sitter.Tree, fileName string, fileContent string) (string, *SourceMap, error) {
 	rootNode := tree.RootNode()
 
 	if rootNode.HasError() {
@@ -72,15 +106,15 @@ func (t *treeSitterVisitor) process(tree *sitter.Tree, fileName string, fileCont
 
 		err := findErrorNodes(rootNode, []byte(fileContent))
 		if err != nil {
-			return "", err
+			return "", nil, err
 		}
 		// skip errors
 	}
-	t.out = &strings.Builder{}
-	t.out.Grow(len(fileContent) + 500)
+	t.out = newTranspiledOutput([]byte(fileContent), &t.last)
 	t.last = 0
+	t.out.WriteString(hoistedFunctionExports(rootNode, []byte(fileContent)))
 	t.visit(rootNode, []byte(fileContent), 0)
-	return t.out.String(), nil
+	return t.out.String(), t.out.sourceMap(fileName), nil
 }
 
 func findErrorNodes(node *sitter.Node, code []byte) error {
@@ -118,7 +152,7 @@ func (t *treeSitterVisitor) visit(node *sitter.Node, sourceCode []byte, depth in
 	switch nodeType {
 	case "jsx_self_closing_element", "jsx_element":
 		{
-			t.out.Write(sourceCode[t.last:node.StartByte()])
+			t.out.copy(t.last, node.StartByte())
 			t.last = node.StartByte()
 			t.visitJSX(node, sourceCode, depth)
 		}
@@ -167,11 +201,14 @@ func (t *treeSitterVisitor) visit(node *sitter.Node, sourceCode []byte, depth in
 			}
 			replaceResult += namedImports
 
-			t.out.Write(sourceCode[t.last:node.StartByte()])
+			t.out.copy(t.last, node.StartByte())
 			t.out.WriteString(replaceResult)
 		}
 	case "export_statement":
-		if node.ChildCount() > 1 {
+		if source := node.ChildByFieldName("source"); source != nil {
+			t.out.copy(t.last, node.StartByte())
+			t.out.WriteString(reexport(node, source, sourceCode))
+		} else if node.ChildCount() > 1 {
 			keyword := node.Child(0).Type()
 			switch keyword {
 			case "export":
@@ -190,10 +227,22 @@ func (t *treeSitterVisitor) visit(node *sitter.Node, sourceCode []byte, depth in
 				for i := 0; i < int(node.ChildCount()); i++ {
 					t.visit(node.Child(i), sourceCode, depth+1)
 				}
-				toRewrite := sourceCode[t.last:nodeEnd]
-				t.out.Write(toRewrite)
+				t.out.copy(t.last, nodeEnd)
 			}
 		}
+	case "call_expression":
+		first := 0
+		if node.Child(0).Type() == "import" {
+			// import("./x.tsx") → module.do_import_dynamic("./x.tsx")
+			t.out.copy(t.last, node.StartByte())
+			t.out.WriteString("module.do_import_dynamic")
+			t.last = node.Child(0).EndByte()
+			first = 1
+		}
+		for i := first; i < int(node.ChildCount()); i++ {
+			t.visit(node.Child(i), sourceCode, depth+1)
+		}
+		t.out.copy(t.last, nodeEnd)
 	case "meta_property":
 		if node.Child(0).Type() == "import" {
 			t.out.WriteString("module.meta")
@@ -212,8 +261,7 @@ func (t *treeSitterVisitor) visit(node *sitter.Node, sourceCode []byte, depth in
 		for i := 0; i < cc; i++ {
 			t.visit(node.Child(i), sourceCode, depth+1)
 		}
-		toRewrite := sourceCode[t.last:nodeEnd]
-		t.out.Write(toRewrite)
+		t.out.copy(t.last, nodeEnd)
 	}
 	t.last = nodeEnd
 }
@@ -231,12 +279,12 @@ func (t *treeSitterVisitor) visitExport(body *sitter.Node, sourceCode []byte, de
 
 	case "function_declaration":
 		// export function add(...) {} → module.exports.add = function add(...) {};
-		name := body.Child(1).Content(sourceCode)
+		name := body.ChildByFieldName("name").Content(sourceCode)
 		bodyExpr = body
 		replaceResult = fmt.Sprintf("module.exports.%s = %s;", name, name)
 
 	case "class_declaration":
-		t.out.Write(sourceCode[t.last:body.StartByte()])
+		t.out.copy(t.last, body.StartByte())
 		t.last = body.StartByte()
 		t.visit(body, sourceCode, depth)
 
@@ -252,23 +300,16 @@ func (t *treeSitterVisitor) visitExport(body *sitter.Node, sourceCode []byte, de
 		// export default coś → module.exports.default = coś;
 		bodyExpr = body.Child(2)
 		replaceResult = "module.default = "
-		t.out.Write(sourceCode[t.last:body.StartByte()])
+		t.out.copy(t.last, body.StartByte())
 		t.out.WriteString(replaceResult)
 		t.visitExport(bodyExpr, sourceCode, depth)
 		return
 
 	case "export_clause":
-		// export { foo, bar } → module.exports.foo = foo; module.exports.bar = bar;
-		exportList := body
+		// export { foo, bar as baz } → module.exports["foo"] = foo; module.exports["baz"] = bar;
 		replacement := []string{}
-		if exportList.ChildCount() > 1 {
-			for i := 1; i < int(exportList.ChildCount())-1; i += 2 {
-				exportName := exportList.Child(i).Content(sourceCode)
-				replacement = append(replacement, fmt.Sprintf("module.exports.%s = %s;", exportName, exportName))
-			}
-		} else {
-			exportName := exportList.Child(0).Content(sourceCode)
-			replacement = append(replacement, fmt.Sprintf("module.exports.%s = %s;", exportName, exportName))
+		for _, spec := range exportSpecifiers(body, sourceCode) {
+			replacement = append(replacement, exportAssignment(spec.exported, spec.local))
 		}
 		replaceResult = strings.Join(replacement, " ")
 
@@ -279,7 +320,7 @@ func (t *treeSitterVisitor) visitExport(body *sitter.Node, sourceCode []byte, de
 		t.replaceWithSpacesFormat(body, sourceCode)
 		return
 	}
-	t.out.Write(sourceCode[t.last:body.StartByte()])
+	t.out.copy(t.last, body.StartByte())
 	t.out.WriteString(replaceResult)
 	if bodyExpr != nil {
 		t.last = bodyExpr.StartByte()
@@ -288,13 +329,102 @@ func (t *treeSitterVisitor) visitExport(body *sitter.Node, sourceCode []byte, de
 	t.last = body.EndByte()
 }
 
+// hoistedFunctionExports exports functions before module code runs, like ESM does.
+// Module imported back in import cycle has them already (see WithImportCycles).
+func hoistedFunctionExports(root *sitter.Node, sourceCode []byte) string {
+	result := ""
+	for i := 0; i < int(root.NamedChildCount()); i++ {
+		node := root.NamedChild(i)
+		if node.Type() != "export_statement" {
+			continue
+		}
+		declaration := node.ChildByFieldName("declaration")
+		if declaration == nil || declaration.Type() != "function_declaration" {
+			continue
+		}
+		if name := declaration.ChildByFieldName("name"); name != nil {
+			result += fmt.Sprintf("module.exports.%s = %s;", name.Content(sourceCode), name.Content(sourceCode))
+		}
+	}
+	return result
+}
+
+type exportSpecifier struct {
+	local    string
+	exported string
+}
+
+func exportSpecifiers(clause *sitter.Node, sourceCode []byte) []exportSpecifier {
+	var result []exportSpecifier
+	for i := 0; i < int(clause.NamedChildCount()); i++ {
+		spec := clause.NamedChild(i)
+		if spec.Type() != "export_specifier" {
+			continue
+		}
+		local := exportName(spec.ChildByFieldName("name"), sourceCode)
+		exported := local
+		if alias := spec.ChildByFieldName("alias"); alias != nil {
+			exported = exportName(alias, sourceCode)
+		}
+		result = append(result, exportSpecifier{local: local, exported: exported})
+	}
+	return result
+}
+
+// exportName returns name of identifier or string ("string name") in export specifier.
+func exportName(node *sitter.Node, sourceCode []byte) string {
+	name := node.Content(sourceCode)
+	if node.Type() == "string" {
+		name = name[1 : len(name)-1]
+	}
+	return name
+}
+
+func exportAssignment(exported string, value string) string {
+	if exported == "default" {
+		return fmt.Sprintf("module.default = %s;", value)
+	}
+	return fmt.Sprintf("module.exports[%s] = %s;", jsString(exported), value)
+}
+
+// reexport rewrites export with source module:
+//
+//	export * from "./a"            → exports of ./a not exported by this module, without default
+//	export * as ns from "./a"      → module.exports["ns"] = module.do_import('./a').exports;
+//	export { x as y } from "./a"   → module.exports["y"] = module.do_import('./a').exports["x"];
+//	export { default } from "./a"  → module.default = module.do_import('./a').default;
+func reexport(node *sitter.Node, source *sitter.Node, sourceCode []byte) string {
+	moduleName := exportName(source, sourceCode)
+	imported := "module.do_import('" + moduleName + "')"
+	var result []string
+	for i := 0; i < int(node.ChildCount()); i++ {
+		child := node.Child(i)
+		switch child.Type() {
+		case "*":
+			result = append(result, "((m) => { for (const k in m) if (!(k in module.exports)) module.exports[k] = m[k] })("+imported+".exports);")
+		case "namespace_export":
+			name := exportName(child.NamedChild(int(child.NamedChildCount())-1), sourceCode)
+			result = append(result, exportAssignment(name, imported+".exports"))
+		case "export_clause":
+			for _, spec := range exportSpecifiers(child, sourceCode) {
+				value := imported + ".exports[" + jsString(spec.local) + "]"
+				if spec.local == "default" {
+					value = imported + ".default"
+				}
+				result = append(result, exportAssignment(spec.exported, value))
+			}
+		}
+	}
+	return strings.Join(result, " ")
+}
+
 func (t *treeSitterVisitor) formatTo(node *sitter.Node, sourceCode []byte) {
-	t.out.Write(sourceCode[t.last:node.StartByte()])
+	t.out.copy(t.last, node.StartByte())
 	t.last = node.StartByte()
 }
 
 func (t *treeSitterVisitor) replaceWithSpacesFormat(node *sitter.Node, sourceCode []byte) {
-	t.out.Write(sourceCode[t.last:node.StartByte()])
+	t.out.copy(t.last, node.StartByte())
 	t.last = node.StartByte()
 	start, end := node.StartByte(), node.EndByte()
 	space := strings.Repeat(" ", int(end-start))
@@ -309,7 +439,7 @@ func (t *treeSitterVisitor) visitJSX(node *sitter.Node, sourceCode []byte, depth
 		t.out.WriteString("wax.Sub(w => w")
 		{
 			identifier := node.ChildByFieldName("name").Content(sourceCode)
-			isComponent := len(identifier) > 0 && unicode.IsUpper([]rune(identifier)[0])
+			isComponent := isComponentName(identifier)
 			if isComponent {
 				t.out.WriteString(".WriteValue(")
 				t.visitComponent(node, sourceCode, depth+1)
@@ -374,7 +504,7 @@ func (t *treeSitterVisitor) visitComponent(node *sitter.Node, sourceCode []byte,
 	switch nodeType {
 	case "jsx_self_closing_element":
 		identifier := node.ChildByFieldName("name").Content(sourceCode)
-		isComponent := len(identifier) > 0 && unicode.IsUpper([]rune(identifier)[0])
+		isComponent := isComponentName(identifier)
 		if isComponent {
 			t.last = node.Child(1).EndByte()
 
@@ -404,7 +534,7 @@ func (t *treeSitterVisitor) visitComponent(node *sitter.Node, sourceCode []byte,
 
 	case "jsx_element":
 		identifier := node.Child(0).ChildByFieldName("name").Content(sourceCode)
-		isComponent := len(identifier) > 0 && unicode.IsUpper([]rune(identifier)[0])
+		isComponent := isComponentName(identifier)
 		if isComponent {
 			t.last = node.Child(0).Child(1).EndByte()
 			t.out.WriteString(identifier)
@@ -512,7 +642,7 @@ func (t *treeSitterVisitor) visitComponentTag(node *sitter.Node, sourceCode []by
 		node := node.Child(i)
 		t.visitTag(node, sourceCode, depth+1)
 	}
-	t.out.Write(sourceCode[t.last:node.EndByte()])
+	t.out.copy(t.last, node.EndByte())
 	t.last = node.EndByte()
 }
 
@@ -524,7 +654,7 @@ func (t *treeSitterVisitor) visitTag(node *sitter.Node, sourceCode []byte, depth
 	case "jsx_self_closing_element":
 		{
 			identifier := node.ChildByFieldName("name").Content(sourceCode)
-			isComponent := len(identifier) > 0 && unicode.IsUpper([]rune(identifier)[0])
+			isComponent := isComponentName(identifier)
 			if isComponent {
 				t.out.WriteString("`)")
 				t.formatTo(node, sourceCode)
@@ -570,7 +700,7 @@ func (t *treeSitterVisitor) visitTag(node *sitter.Node, sourceCode []byte, depth
 	case "jsx_element":
 		{
 			identifier := node.Child(0).ChildByFieldName("name").Content(sourceCode)
-			isComponent := len(identifier) > 0 && unicode.IsUpper([]rune(identifier)[0])
+			isComponent := isComponentName(identifier)
 			if isComponent {
 				t.out.WriteString("`)")
 				t.out.WriteString(".WriteValue(")
@@ -620,7 +750,7 @@ func (t *treeSitterVisitor) visitTag(node *sitter.Node, sourceCode []byte, depth
 			return
 		}
 	case "jsx_expression":
-		t.out.Write(sourceCode[t.last:node.StartByte()])
+		t.out.copy(t.last, node.StartByte())
 		t.out.WriteString("`)")
 		t.out.WriteString(".WriteValue(")
 		{
@@ -635,7 +765,7 @@ func (t *treeSitterVisitor) visitTag(node *sitter.Node, sourceCode []byte, depth
 		return
 	case "jsx_attribute":
 		handled := false
-		t.out.Write(sourceCode[t.last:node.StartByte()])
+		t.out.copy(t.last, node.StartByte())
 		if node.Child(0).Type() == "property_identifier" {
 			attrName := node.Child(0).Content(sourceCode)
 
@@ -686,7 +816,7 @@ func (t *treeSitterVisitor) visitTag(node *sitter.Node, sourceCode []byte, depth
 		t.visitTag(node, sourceCode, depth+1)
 	}
 
-	t.out.Write(sourceCode[t.last:node.EndByte()])
+	t.out.copy(t.last, node.EndByte())
 	t.last = node.EndByte()
 }
 
@@ -0,0 +1,64 @@
+package wax
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"path/filepath"
+)
+
+// TranspiledContentProvider is implemented by view resolvers serving already transpiled modules.
+// Engine uses it instead of GetContent and skips transpiling.
+type TranspiledContentProvider interface {
+	GetTranspiledContent(url url.URL) (string, *SourceMap, error)
+}
+
+// NewBundleViewResolver resolves views from bundle written by Engine.WriteBundle (or `wax build`).
+// Options are the same as of NewFsViewResolver, pass options used to write the bundle (e.g. WithNodeModules).
+func NewBundleViewResolver(bundle []byte, options ...FSViewResolverOption) (ViewResolver, error) {
+	archive, err := zip.NewReader(bytes.NewReader(bundle), int64(len(bundle)))
+	if err != nil {
+		return nil, err
+	}
+	result := &viewResolverBundle{
+		viewResolverFS: viewResolverFS{
+			fs:         archive,
+			resolve:    simpleViewResolver(defaultViewExtensions...),
+			extensions: moduleExtensions,
+		},
+	}
+	for _, option := range options {
+		option(&result.viewResolverFS)
+	}
+	return result, nil
+}
+
+type viewResolverBundle struct {
+	viewResolverFS
+}
+
+const bundleSourceMapExt = ".map"
+
+func (r *viewResolverBundle) GetTranspiledContent(u url.URL) (string, *SourceMap, error) {
+	code, err := r.GetContent(u)
+	if err != nil {
+		return "", nil, err
+	}
+	f, _ := filepath.Rel("/", u.Path)
+	data, err := fs.ReadFile(r.fs, filepath.ToSlash(f)+bundleSourceMapExt)
+	if errors.Is(err, fs.ErrNotExist) {
+		return code, nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	sourceMap := &SourceMap{}
+	if err := json.Unmarshal(data, sourceMap); err != nil {
+		return "", nil, err
+	}
+	sourceMap.File = u.String()
+	return code, sourceMap, nil
+}
@@ -11,20 +11,29 @@ import (
 	"strings"
 )
 
-func NewFsViewResolver(fs fs.FS) ViewResolver {
-	return &viewResolverFS{
-		fs:      fs,
-		resolve: simpleViewResolver(".tsx", ".jsx"),
-	}
+var defaultViewExtensions = []string{".tsx", ".jsx"}
+
+// moduleExtensions are extensions of files listed as modules - views and assets imported by them.
+var moduleExtensions = append(slices.Clone(defaultViewExtensions), assetExtensions...)
+
+func NewFsViewResolver(fs fs.FS, options ...FSViewResolverOption) ViewResolver {
+	return NewFsViewResolverCustom(fs, simpleViewResolver(defaultViewExtensions...), options...)
 }
 
-func NewFsViewResolverCustom(fs fs.FS, r FSViewResolveFunc) ViewResolver {
-	return &viewResolverFS{
-		fs:      fs,
-		resolve: r,
+func NewFsViewResolverCustom(fs fs.FS, r FSViewResolveFunc, options ...FSViewResolverOption) ViewResolver {
+	result := &viewResolverFS{
+		fs:         fs,
+		resolve:    r,
+		extensions: moduleExtensions,
+	}
+	for _, option := range options {
+		option(result)
 	}
+	return result
 }
 
+type FSViewResolverOption func(*viewResolverFS)
+
 type FSViewResolveFunc = func(fs fs.FS, viewName string) (*url.URL, error)
 
 func simpleViewResolver(ext ...string) FSViewResolveFunc {
@@ -53,16 +62,21 @@ func simpleViewResolver(ext ...string) FSViewResolveFunc {
 			}
 		}
 		return nil, &os.PathError{
-			Op:   "not_found",
+			Op:   "open",
 			Path: viewName,
-			Err:  errors.New("could not resolve view file"),
+			Err:  fs.ErrNotExist,
 		}
 	}
 }
 
 type viewResolverFS struct {
-	fs      fs.FS
-	resolve FSViewResolveFunc
+	fs         fs.FS
+	resolve    FSViewResolveFunc
+	extensions []string
+
+	importMap   map[string]string
+	nodeModules bool
+	tsConfig    *tsConfigLoader
 }
 
 func (r *viewResolverFS) ResolveViewFile(viewName string) (*url.URL, error) {
@@ -70,12 +84,12 @@ func (r *viewResolverFS) ResolveViewFile(viewName string) (*url.URL, error) {
 }
 
 func (r *viewResolverFS) ResolveModuleFile(fromModule ModuleMeta, importPath string) (*url.URL, error) {
+	if importPath != "" && importPath[0] != '.' {
+		return r.resolveBare(fromModule, importPath)
+	}
 	if len(importPath) < 3 {
 		return nil, errors.New("invalid import path")
 	}
-	if importPath[0] != '.' {
-		return nil, errors.New("only relative path is supported")
-	}
 
 	fromDir := filepath.Dir(fromModule.URL.Path)
 	f, _ := filepath.Rel("/", filepath.Join(filepath.Join(fromDir, importPath)))
@@ -93,3 +107,29 @@ func (r *viewResolverFS) GetContent(url url.URL) (string, error) {
 
 	return string(content), nil
 }
+
+// ListModules returns all files with view or asset extension.
+func (r *viewResolverFS) ListModules() ([]*url.URL, error) {
+	var result []*url.URL
+	// tsconfig files are not modules, they may have comments
+	// (config of layered resolver can be in other layer)
+	configFiles, err := r.tsConfigFiles()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	err = fs.WalkDir(r.fs, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !slices.Contains(r.extensions, filepath.Ext(path)) || slices.Contains(configFiles, path) {
+			return nil
+		}
+		u, err := r.resolve(r.fs, path)
+		if err != nil {
+			return err
+		}
+		result = append(result, u)
+		return nil
+	})
+	return result, err
+}
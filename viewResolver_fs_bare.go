@@ -0,0 +1,243 @@
+package wax
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// WithImportMap maps bare import specifiers to paths from views root, like browser import maps.
+// Key ending with "/" maps all specifiers starting with it, e.g. {"ui/": "./components/ui/"}.
+func WithImportMap(imports map[string]string) FSViewResolverOption {
+	return func(r *viewResolverFS) {
+		r.importMap = imports
+	}
+}
+
+// WithNodeModules resolves bare import specifiers from node_modules directories of fs,
+// using `exports`, `module` and `main` fields of package.json. Packages must be ES modules.
+func WithNodeModules() FSViewResolverOption {
+	return func(r *viewResolverFS) {
+		r.nodeModules = true
+	}
+}
+
+// packageConditions are export conditions of package.json, in order of preference.
+var packageConditions = []string{"import", "module", "default"}
+
+// scriptExtensions are tried for imported files without extension.
+var scriptExtensions = []string{".js", ".mjs", ".ts", ".tsx", ".jsx"}
+
+func (r *viewResolverFS) resolveBare(fromModule ModuleMeta, specifier string) (*url.URL, error) {
+	file, ok, err := r.mapSpecifier(specifier)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return r.resolve(r.fs, file)
+	}
+	return r.resolveNodeModules(fromModule, specifier)
+}
+
+// mapSpecifier maps bare specifier to file path from fs root with import map or tsconfig paths.
+func (r *viewResolverFS) mapSpecifier(specifier string) (string, bool, error) {
+	if mapped, ok := r.mapImport(specifier); ok {
+		file := strings.TrimPrefix(path.Clean("/"+mapped), "/")
+		if found, ok := r.findFile(file); ok {
+			return found, true, nil
+		}
+		return file, true, nil
+	}
+	if r.tsConfig != nil {
+		return r.resolveTSConfigPath(specifier)
+	}
+	return "", false, nil
+}
+
+func (r *viewResolverFS) resolveNodeModules(fromModule ModuleMeta, specifier string) (*url.URL, error) {
+	if !r.nodeModules {
+		return nil, errors.New("only relative path is supported")
+	}
+
+	packageName, subpath := splitPackageSpecifier(specifier)
+	for dir := path.Dir(fromModule.URL.Path); ; dir = path.Dir(dir) {
+		packageDir := strings.TrimPrefix(path.Join(dir, "node_modules", packageName), "/")
+		if stat, err := fs.Stat(r.fs, packageDir); err == nil && stat.IsDir() {
+			return r.resolvePackage(packageDir, subpath)
+		}
+		if dir == "/" || dir == "." {
+			return nil, fmt.Errorf("package '%s' not found in node_modules", packageName)
+		}
+	}
+}
+
+// mapImport returns import map path for specifier. Longest matching prefix wins.
+func (r *viewResolverFS) mapImport(specifier string) (string, bool) {
+	if mapped, ok := r.importMap[specifier]; ok {
+		return mapped, true
+	}
+	prefixes := make([]string, 0, len(r.importMap))
+	for k := range r.importMap {
+		if strings.HasSuffix(k, "/") && strings.HasPrefix(specifier, k) {
+			prefixes = append(prefixes, k)
+		}
+	}
+	if len(prefixes) == 0 {
+		return "", false
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+	return r.importMap[prefixes[0]] + strings.TrimPrefix(specifier, prefixes[0]), true
+}
+
+// splitPackageSpecifier splits "@scope/name/sub/path" to package name and subpath "./sub/path".
+func splitPackageSpecifier(specifier string) (string, string) {
+	parts := strings.SplitN(specifier, "/", 3)
+	n := 1
+	if strings.HasPrefix(specifier, "@") && len(parts) > 1 {
+		n = 2
+	}
+	if len(parts) <= n {
+		return specifier, "."
+	}
+	return strings.Join(parts[:n], "/"), "./" + strings.Join(parts[n:], "/")
+}
+
+type packageJSON struct {
+	Exports any    `json:"exports"`
+	Module  string `json:"module"`
+	Main    string `json:"main"`
+}
+
+func (r *viewResolverFS) resolvePackage(packageDir string, subpath string) (*url.URL, error) {
+	var pkg packageJSON
+	if data, err := fs.ReadFile(r.fs, path.Join(packageDir, "package.json")); err == nil {
+		if err := json.Unmarshal(data, &pkg); err != nil {
+			return nil, fmt.Errorf("invalid package.json of '%s': %w", packageDir, err)
+		}
+	}
+
+	if pkg.Exports != nil {
+		target, ok, err := packageExport(pkg.Exports, subpath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid package.json of '%s': %w", packageDir, err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not exported by package '%s'", subpath, packageDir)
+		}
+		return r.resolveFile(path.Join(packageDir, target))
+	}
+	if subpath == "." {
+		for _, entry := range []string{pkg.Module, pkg.Main, "index.js"} {
+			if entry != "" {
+				return r.resolveFile(path.Join(packageDir, entry))
+			}
+		}
+	}
+	return r.resolveFile(path.Join(packageDir, subpath))
+}
+
+// packageExport returns target of subpath in package.json `exports`, with subpath patterns ("./*") support.
+func packageExport(exports any, subpath string) (string, bool, error) {
+	subpaths, isMap := exports.(map[string]any)
+	if isMap {
+		isSubpaths, err := hasSubpathKeys(subpaths)
+		if err != nil {
+			return "", false, err
+		}
+		isMap = isSubpaths
+	}
+	if !isMap {
+		if subpath != "." {
+			return "", false, nil
+		}
+		target, ok := exportTarget(exports)
+		return target, ok, nil
+	}
+	if target, ok := subpaths[subpath]; ok {
+		t, ok := exportTarget(target)
+		return t, ok, nil
+	}
+	keys := make([]string, 0, len(subpaths))
+	for key := range subpaths {
+		keys = append(keys, key)
+	}
+	// the most specific pattern wins
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	for _, key := range keys {
+		prefix, suffix, isPattern := strings.Cut(key, "*")
+		if !isPattern || !strings.HasPrefix(subpath, prefix) || !strings.HasSuffix(subpath, suffix) || len(subpath) < len(prefix)+len(suffix) {
+			continue
+		}
+		if t, ok := exportTarget(subpaths[key]); ok {
+			return strings.ReplaceAll(t, "*", subpath[len(prefix):len(subpath)-len(suffix)]), true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// hasSubpathKeys reports whether `exports` object maps subpaths (keys starting with ".") rather than conditions.
+// Mixing both kinds of keys is invalid.
+func hasSubpathKeys(m map[string]any) (bool, error) {
+	subpaths := 0
+	for k := range m {
+		if strings.HasPrefix(k, ".") {
+			subpaths++
+		}
+	}
+	if subpaths > 0 && subpaths < len(m) {
+		return false, errors.New(`"exports" mixes subpaths and conditions`)
+	}
+	return subpaths > 0, nil
+}
+
+// exportTarget picks path from export target - string, conditions or list of alternatives.
+func exportTarget(target any) (string, bool) {
+	switch t := target.(type) {
+	case string:
+		return t, true
+	case []any:
+		for _, alternative := range t {
+			if p, ok := exportTarget(alternative); ok {
+				return p, true
+			}
+		}
+	case map[string]any:
+		for _, condition := range packageConditions {
+			if conditional, ok := t[condition]; ok {
+				if p, ok := exportTarget(conditional); ok {
+					return p, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveFile resolves file path from fs root, trying script extensions and index files.
+func (r *viewResolverFS) resolveFile(file string) (*url.URL, error) {
+	if found, ok := r.findFile(file); ok {
+		return r.resolve(r.fs, found)
+	}
+	return r.resolve(r.fs, file)
+}
+
+func (r *viewResolverFS) findFile(file string) (string, bool) {
+	candidates := []string{file}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, file+ext)
+	}
+	for _, ext := range scriptExtensions {
+		candidates = append(candidates, path.Join(file, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if stat, err := fs.Stat(r.fs, candidate); err == nil && !stat.IsDir() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
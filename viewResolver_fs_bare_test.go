@@ -0,0 +1,73 @@
+package wax_test
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_FsViewResolver_BareSpecifiers(t *testing.T) {
+	fs := fstest.MapFS{
+		"View.tsx": &fstest.MapFile{Data: []byte(`
+            import clsx from "clsx"
+            import { formatDate } from "@acme/dates/format"
+            import { Button } from "@acme/ui"
+            import { Card } from "ui/card"
+            import { cast } from "@acme/cast"
+            import { Badge } from "ui/badge.js"
+            export function View() {
+                return <main class={clsx("a", false && "b", "c")}><Button/><Card/>{formatDate()}{cast("!")}<Badge/></main>
+            }`)},
+		"components/card.tsx": &fstest.MapFile{Data: []byte(`export function Card() { return <section>card</section> }`)},
+		"components/badge.js": &fstest.MapFile{Data: []byte(`export function Badge() { return <b>badge</b> }`)},
+
+		"node_modules/clsx/package.json": &fstest.MapFile{Data: []byte(`{"main": "dist/clsx.cjs", "module": "dist/clsx.mjs"}`)},
+		"node_modules/clsx/dist/clsx.mjs": &fstest.MapFile{Data: []byte(`
+            export default function clsx(...args) { return args.filter(Boolean).join("-") }`)},
+
+		"node_modules/@acme/dates/package.json": &fstest.MapFile{Data: []byte(`{
+            "exports": {".": "./index.js", "./*": {"require": "./cjs/*.cjs", "import": "./esm/*.js"}}}`)},
+		"node_modules/@acme/dates/esm/format.js": &fstest.MapFile{Data: []byte(`
+            import { pad } from "./pad.js"
+            export function formatDate() { return pad("7") }`)},
+		"node_modules/@acme/dates/esm/pad.js": &fstest.MapFile{Data: []byte(`
+            export function pad(s) { return "0" + s }`)},
+
+		"node_modules/@acme/cast/package.json": &fstest.MapFile{Data: []byte(`{"exports": "./index.ts"}`)},
+		"node_modules/@acme/cast/index.ts": &fstest.MapFile{Data: []byte(`
+            export function cast(v: unknown): string { return <string>v }`)},
+
+		"node_modules/@acme/mixed/package.json": &fstest.MapFile{Data: []byte(`{"exports": {".": "./index.js", "import": "./esm.js"}}`)},
+
+		"node_modules/@acme/ui/package.json": &fstest.MapFile{Data: []byte(`{"exports": {"import": "./src/index.tsx", "default": "./dist/index.cjs"}}`)},
+		"node_modules/@acme/ui/src/index.tsx": &fstest.MapFile{Data: []byte(`
+            export { Button } from "./button"`)},
+		"node_modules/@acme/ui/src/button.tsx": &fstest.MapFile{Data: []byte(`
+            export function Button() { return <button>ok</button> }`)},
+	}
+	resolver := wax.NewFsViewResolver(fs, wax.WithNodeModules(), wax.WithImportMap(map[string]string{"ui/": "./components/"}))
+
+	buf := bytes.NewBufferString("")
+	if err := wax.New(resolver).Render(buf, "View", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "bare", `<main class="a-c"><button>ok</button><section>card</section>07!<b>badge</b></main>`, buf.String())
+
+	t.Run("mixed_exports", func(t *testing.T) {
+		_, err := resolver.ResolveModuleFile(wax.ModuleMeta{URL: &url.URL{Scheme: "file", Path: "/View.tsx"}}, "@acme/mixed")
+		if err == nil || !strings.Contains(err.Error(), "mixes subpaths and conditions") {
+			t.Errorf("expected invalid exports error, got %v", err)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		err := wax.New(wax.NewFsViewResolver(fs)).Render(bytes.NewBufferString(""), "View", nil)
+		if err == nil {
+			t.Error("bare specifier resolved without WithNodeModules")
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package wax
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WithTSConfig applies `baseUrl` and `paths` of tsconfig.json file (path from fs root, e.g. "tsconfig.json")
+// to bare import specifiers, so aliases like "@/components/*" work the same as in editor.
+// Config is read once, with its `extends` chain.
+func WithTSConfig(file string) FSViewResolverOption {
+	return func(r *viewResolverFS) {
+		r.tsConfig = &tsConfigLoader{file: strings.TrimPrefix(path.Clean("/"+file), "/")}
+	}
+}
+
+type tsConfigLoader struct {
+	file   string
+	once   sync.Once
+	config tsConfigPaths
+	err    error
+	// files are config file and files it extends
+	files []string
+}
+
+// tsConfigPaths are compiler options used by resolver, with directories from fs root.
+type tsConfigPaths struct {
+	baseURL   string
+	hasBase   bool
+	paths     map[string][]string
+	pathsBase string
+}
+
+type tsConfigFile struct {
+	Extends         any `json:"extends"`
+	CompilerOptions struct {
+		BaseURL *string             `json:"baseUrl"`
+		Paths   map[string][]string `json:"paths"`
+	} `json:"compilerOptions"`
+}
+
+// loadedTSConfig reads config once.
+func (r *viewResolverFS) loadedTSConfig() (*tsConfigLoader, error) {
+	l := r.tsConfig
+	l.once.Do(func() {
+		seen := map[string]bool{}
+		l.config, l.err = r.loadTSConfig(l.file, seen)
+		for file := range seen {
+			l.files = append(l.files, file)
+		}
+		sort.Strings(l.files)
+	})
+	return l, l.err
+}
+
+// tsConfigFiles returns files of tsconfig used by resolver, they are copied to bundles.
+func (r *viewResolverFS) tsConfigFiles() ([]string, error) {
+	if r.tsConfig == nil {
+		return nil, nil
+	}
+	l, err := r.loadedTSConfig()
+	if err != nil {
+		return nil, err
+	}
+	return l.files, nil
+}
+
+func (r *viewResolverFS) resolveTSConfigPath(specifier string) (string, bool, error) {
+	l, err := r.loadedTSConfig()
+	if err != nil {
+		return "", false, err
+	}
+
+	config := l.config
+	if targets, wildcard, ok := matchTSPath(config.paths, specifier); ok {
+		for _, target := range targets {
+			file := path.Join(config.pathsBase, strings.Replace(target, "*", wildcard, 1))
+			if found, ok := r.findFile(file); ok {
+				return found, true, nil
+			}
+		}
+		// not resolved by paths, falls back to baseUrl and node_modules
+	}
+	if config.hasBase {
+		if found, ok := r.findFile(path.Join(config.baseURL, specifier)); ok {
+			return found, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// matchTSPath returns targets of the most specific `paths` pattern matching specifier and text matched by "*".
+func matchTSPath(paths map[string][]string, specifier string) ([]string, string, bool) {
+	if targets, ok := paths[specifier]; ok {
+		return targets, "", true
+	}
+	patterns := make([]string, 0, len(paths))
+	for pattern := range paths {
+		prefix, suffix, isPattern := strings.Cut(pattern, "*")
+		if isPattern && strings.HasPrefix(specifier, prefix) && strings.HasSuffix(specifier, suffix) && len(specifier) >= len(prefix)+len(suffix) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil, "", false
+	}
+	sort.Slice(patterns, func(i, j int) bool { return strings.Index(patterns[i], "*") > strings.Index(patterns[j], "*") })
+	prefix, suffix, _ := strings.Cut(patterns[0], "*")
+	return paths[patterns[0]], specifier[len(prefix) : len(specifier)-len(suffix)], true
+}
+
+// loadTSConfig reads config file and configs it extends. Options of the file override extended ones.
+func (r *viewResolverFS) loadTSConfig(file string, seen map[string]bool) (tsConfigPaths, error) {
+	var result tsConfigPaths
+	if seen[file] {
+		return result, fmt.Errorf("tsconfig %s extends itself", file)
+	}
+	seen[file] = true
+
+	data, err := fs.ReadFile(r.fs, file)
+	if err != nil {
+		return result, err
+	}
+	var config tsConfigFile
+	if err := json.Unmarshal(stripJSONComments(data), &config); err != nil {
+		return result, fmt.Errorf("invalid tsconfig %s: %w", file, err)
+	}
+
+	var extends []string
+	switch e := config.Extends.(type) {
+	case string:
+		extends = []string{e}
+	case []any:
+		for _, v := range e {
+			if s, ok := v.(string); ok {
+				extends = append(extends, s)
+			}
+		}
+	}
+	dir := path.Dir(file)
+	for _, base := range extends {
+		baseFile, err := r.tsConfigFile(dir, base)
+		if err != nil {
+			return result, err
+		}
+		baseConfig, err := r.loadTSConfig(baseFile, seen)
+		if err != nil {
+			return result, err
+		}
+		if baseConfig.hasBase {
+			result.baseURL, result.hasBase = baseConfig.baseURL, true
+		}
+		if baseConfig.paths != nil {
+			result.paths, result.pathsBase = baseConfig.paths, baseConfig.pathsBase
+		}
+	}
+
+	options := config.CompilerOptions
+	if options.BaseURL != nil {
+		result.baseURL, result.hasBase = path.Join(dir, *options.BaseURL), true
+	}
+	if options.Paths != nil {
+		result.paths, result.pathsBase = options.Paths, dir
+	}
+	// paths are relative to baseUrl, when it is set
+	if result.paths != nil && result.hasBase {
+		result.pathsBase = result.baseURL
+	}
+	return result, nil
+}
+
+// tsConfigFile resolves `extends` - relative file or config from node_modules package.
+func (r *viewResolverFS) tsConfigFile(dir string, extends string) (string, error) {
+	candidates := []string{}
+	if strings.HasPrefix(extends, ".") {
+		candidates = append(candidates, path.Join(dir, extends), path.Join(dir, extends)+".json")
+	} else {
+		for d := dir; ; d = path.Dir(d) {
+			base := path.Join(d, "node_modules", extends)
+			candidates = append(candidates, base, base+".json", path.Join(base, "tsconfig.json"))
+			if d == "." {
+				break
+			}
+		}
+	}
+	for _, candidate := range candidates {
+		if stat, err := fs.Stat(r.fs, candidate); err == nil && !stat.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("could not find tsconfig '%s' extended from %s", extends, dir)
+}
+
+// stripJSONComments removes comments and trailing commas allowed in tsconfig files.
+func stripJSONComments(data []byte) []byte {
+	return stripTrailingCommas(stripComments(data))
+}
+
+func stripComments(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end < 0 {
+				i = len(data)
+			} else {
+				i += end + 3
+			}
+			out.WriteByte(' ')
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
+
+// stripTrailingCommas removes commas before closing brace or bracket, data must be without comments.
+func stripTrailingCommas(data []byte) []byte {
+	var out bytes.Buffer
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case inString:
+			out.WriteByte(c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out.WriteByte(data[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out.WriteByte(c)
+		case c == ',':
+			rest := bytes.TrimLeft(data[i+1:], " \t\r\n")
+			if len(rest) > 0 && (rest[0] == '}' || rest[0] == ']') {
+				continue
+			}
+			out.WriteByte(c)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,56 @@
+package wax_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_FsViewResolver_TSConfig(t *testing.T) {
+	fs := fstest.MapFS{
+		"tsconfig.json": &fstest.MapFile{Data: []byte(`{
+            // views config
+            "extends": "./config/tsconfig.base.json",
+            "compilerOptions": {
+                "jsx": "react-jsx", /* editor only */
+                "baseUrl": ".",
+            },
+        }`)},
+		"config/tsconfig.base.json": &fstest.MapFile{Data: []byte(`{
+            "compilerOptions": {
+                "paths": {
+                    "@/*": ["./src/*", "./generated/*"],
+                    "@ui/*": ["./components/ui/*"],
+                    "#config": ["./config/site.ts"],
+                    "lib/*": ["./missing/*"]
+                }, // aliases
+            }
+        }`)},
+		"src/View.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button } from "@ui/button"
+            import { Title } from "@/parts/title"
+            import { Version } from "@/version"
+            import { site } from "#config"
+            import { helper } from "lib/helper"
+            import { Icon } from "@ui/icons"
+            export default function View() {
+                return <main><Title>{site}</Title><Button/><Version/>{helper()}<Icon/></main>
+            }`)},
+		"src/parts/title.tsx":                 &fstest.MapFile{Data: []byte(`export function Title(p) { return <h1>{p.children}</h1> }`)},
+		"generated/version.tsx":               &fstest.MapFile{Data: []byte(`export function Version() { return <i>v1</i> }`)},
+		"components/ui/button/index.tsx":      &fstest.MapFile{Data: []byte(`export function Button() { return <button>ok</button> }`)},
+		"config/site.ts":                      &fstest.MapFile{Data: []byte(`export const site = "wax"`)},
+		"lib/helper.ts":                       &fstest.MapFile{Data: []byte(`export function helper() { return "helper" }`)},
+		"node_modules/@ui/icons/package.json": &fstest.MapFile{Data: []byte(`{"module": "icons.js"}`)},
+		"node_modules/@ui/icons/icons.js":     &fstest.MapFile{Data: []byte(`export function Icon() { return "icon" }`)},
+	}
+
+	buf := bytes.NewBufferString("")
+	err := wax.New(wax.NewFsViewResolver(fs, wax.WithTSConfig("tsconfig.json"), wax.WithNodeModules())).Render(buf, "src/View", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "tsconfig", "<main><h1>wax</h1><button>ok</button><i>v1</i>helpericon</main>", buf.String())
+}
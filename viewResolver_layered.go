@@ -0,0 +1,147 @@
+package wax
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// superImport prefix imports file overridden by the importing module, from layers below it.
+const superImport = "super:"
+
+// NewLayeredViewResolver resolves views and imports from ordered layers (e.g. tenant, theme, base), the first layer wins.
+// Imports are resolved from all layers wherever the importing module is, so any component can be overridden.
+// Override imports the file it overrides with "super:" prefix, e.g. `import { Button } from "super:./Button.tsx"`.
+// Options are applied to every layer. Import map and tsconfig paths map bare specifiers over all layers,
+// so aliased imports can be overridden too (config files can be in any layer).
+func NewLayeredViewResolver(layers []fs.FS, options ...FSViewResolverOption) ViewResolver {
+	result := &viewResolverLayered{
+		merged: NewFsViewResolver(layeredFS(layers), options...).(*viewResolverFS),
+	}
+	for _, layer := range layers {
+		result.layers = append(result.layers, NewFsViewResolver(layer, options...).(*viewResolverFS))
+	}
+	return result
+}
+
+type viewResolverLayered struct {
+	layers []*viewResolverFS
+	// merged maps bare specifiers to paths, with files of all layers
+	merged *viewResolverFS
+}
+
+// layeredFS opens file from the first layer having it.
+type layeredFS []fs.FS
+
+func (l layeredFS) Open(name string) (fs.File, error) {
+	for _, layer := range l {
+		f, err := layer.Open(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (r *viewResolverLayered) ResolveViewFile(viewName string) (*url.URL, error) {
+	return r.find(0, func(layer *viewResolverFS) (*url.URL, error) {
+		return layer.ResolveViewFile(viewName)
+	})
+}
+
+func (r *viewResolverLayered) ResolveModuleFile(fromModule ModuleMeta, importPath string) (*url.URL, error) {
+	first := 0
+	if rest, isSuper := strings.CutPrefix(importPath, superImport); isSuper {
+		layer, ok := layerOf(fromModule.URL)
+		if !ok {
+			return nil, fmt.Errorf("'%s' can be imported only by module of layered resolver", importPath)
+		}
+		importPath, first = rest, layer+1
+	}
+	inLayer := fromModule
+	inLayer.URL = layerURL(fromModule.URL, "")
+	if importPath != "" && importPath[0] != '.' {
+		file, ok, err := r.merged.mapSpecifier(importPath)
+		if err != nil {
+			return nil, err
+		}
+		return r.find(first, func(layer *viewResolverFS) (*url.URL, error) {
+			if ok {
+				return layer.resolve(layer.fs, file)
+			}
+			return layer.resolveNodeModules(inLayer, importPath)
+		})
+	}
+	return r.find(first, func(layer *viewResolverFS) (*url.URL, error) {
+		return layer.ResolveModuleFile(inLayer, importPath)
+	})
+}
+
+func (r *viewResolverLayered) GetContent(u url.URL) (string, error) {
+	layer, ok := layerOf(&u)
+	if !ok || layer >= len(r.layers) {
+		return "", fmt.Errorf("module %s is not from layered resolver", u.String())
+	}
+	return r.layers[layer].GetContent(*layerURL(&u, ""))
+}
+
+// ListModules returns modules of all layers, files overridden by upper layers are skipped.
+func (r *viewResolverLayered) ListModules() ([]*url.URL, error) {
+	var result []*url.URL
+	seen := map[string]bool{}
+	for i, layer := range r.layers {
+		modules, err := layer.ListModules()
+		if err != nil {
+			return nil, err
+		}
+		for _, module := range modules {
+			if seen[module.Path] {
+				continue
+			}
+			seen[module.Path] = true
+			result = append(result, layerURL(module, layerHost(i)))
+		}
+	}
+	return result, nil
+}
+
+// find returns URL from the first layer, starting from layer first, where resolve succeeds.
+func (r *viewResolverLayered) find(first int, resolve func(layer *viewResolverFS) (*url.URL, error)) (*url.URL, error) {
+	var errs []error
+	for i := first; i < len(r.layers); i++ {
+		u, err := resolve(r.layers[i])
+		if err == nil {
+			return layerURL(u, layerHost(i)), nil
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no layer below to resolve from")
+	}
+	return nil, errs[0]
+}
+
+// Layer of module is kept in host of its URL, e.g. file://layer-1/components/Button.tsx.
+const layerHostPrefix = "layer-"
+
+func layerHost(layer int) string {
+	return layerHostPrefix + strconv.Itoa(layer)
+}
+
+func layerOf(u *url.URL) (int, bool) {
+	n, isLayer := strings.CutPrefix(u.Host, layerHostPrefix)
+	if !isLayer {
+		return 0, false
+	}
+	layer, err := strconv.Atoi(n)
+	return layer, err == nil
+}
+
+func layerURL(u *url.URL, host string) *url.URL {
+	result := *u
+	result.Host = host
+	return &result
+}
@@ -0,0 +1,115 @@
+package wax_test
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/michal-laskowski/wax"
+)
+
+func Test_LayeredViewResolver(t *testing.T) {
+	base := fstest.MapFS{
+		"Home.tsx": &fstest.MapFile{Data: []byte(`
+            import { Header } from "./parts/Header.tsx"
+            import { Button } from "./parts/Button.tsx"
+            export function Home() { return <main><Header/><Button/></main> }`)},
+		"parts/Header.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button } from "./Button.tsx"
+            export function Header() { return <header>base<Button/></header> }`)},
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            export function Button(p) { return <button class={p.class ?? "base"}>ok</button> }`)},
+	}
+	theme := fstest.MapFS{
+		"parts/Header.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button } from "./Button.tsx"
+            export function Header() { return <header>theme<Button/></header> }`)},
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button as Base } from "super:./Button.tsx"
+            export function Button() { return <Base class="theme"/> }`)},
+	}
+	tenant := fstest.MapFS{
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button as Themed } from "super:./Button.tsx"
+            export function Button() { return <span class="tenant"><Themed/></span> }`)},
+	}
+
+	checks := []struct {
+		name     string
+		layers   []fs.FS
+		expected string
+	}{
+		{
+			name:     "base",
+			layers:   []fs.FS{base},
+			expected: `<main><header>base<button class="base">ok</button></header><button class="base">ok</button></main>`,
+		},
+		{
+			name:     "theme",
+			layers:   []fs.FS{theme, base},
+			expected: `<main><header>theme<button class="theme">ok</button></header><button class="theme">ok</button></main>`,
+		},
+		{
+			name:     "tenant",
+			layers:   []fs.FS{tenant, theme, base},
+			expected: `<main><header>theme<span class="tenant"><button class="theme">ok</button></span></header><span class="tenant"><button class="theme">ok</button></span></main>`,
+		},
+	}
+	for _, check := range checks {
+		t.Run(check.name, func(t *testing.T) {
+			engine := wax.New(wax.NewLayeredViewResolver(check.layers))
+			if err := engine.PrecompileAll(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			buf := bytes.NewBufferString("")
+			if err := engine.Render(buf, "Home", nil); err != nil {
+				t.Fatal(err)
+			}
+			compareHTML(t, check.name, check.expected, buf.String())
+		})
+	}
+
+	t.Run("bundle", func(t *testing.T) {
+		out := bytes.NewBuffer(nil)
+		err := wax.New(wax.NewLayeredViewResolver([]fs.FS{tenant, theme, base})).WriteBundle(context.Background(), out)
+		if err == nil || out.Len() != 0 {
+			t.Error("expected error of bundling layered views")
+		}
+	})
+
+	t.Run("super_in_bottom_layer", func(t *testing.T) {
+		err := wax.New(wax.NewLayeredViewResolver([]fs.FS{theme})).Render(bytes.NewBufferString(""), "parts/Button", nil)
+		if err == nil {
+			t.Error("expected error of super import without layer below")
+		}
+	})
+}
+
+func Test_LayeredViewResolver_AliasedOverride(t *testing.T) {
+	base := fstest.MapFS{
+		"tsconfig.json": &fstest.MapFile{Data: []byte(`{"compilerOptions": {"paths": {"@/*": ["./*"]}}}`)},
+		"Home.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button } from "@/parts/Button"
+            export function Home() { return <main><Button/></main> }`)},
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            export function Button() { return <button>base</button> }`)},
+	}
+	tenant := fstest.MapFS{
+		"parts/Button.tsx": &fstest.MapFile{Data: []byte(`
+            import { Button as Base } from "super:@/parts/Button"
+            export function Button() { return <span class="tenant"><Base/></span> }`)},
+	}
+
+	resolver := wax.NewLayeredViewResolver([]fs.FS{tenant, base}, wax.WithTSConfig("tsconfig.json"))
+	engine := wax.New(resolver)
+	if err := engine.PrecompileAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	buf := bytes.NewBufferString("")
+	if err := engine.Render(buf, "Home", nil); err != nil {
+		t.Fatal(err)
+	}
+	compareHTML(t, "aliased", `<main><span class="tenant"><button>base</button></span></main>`, buf.String())
+}